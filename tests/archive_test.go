@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVaultArchive(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	for _, data := range []map[string]interface{}{{"v": "1"}, {"v": "2"}} {
+		err = v.Write("app/a", data)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	numbers := map[string]interface{}{"b": "b", "int": json.Number("9007199254740993"), "large": json.Number("1000000000000000000000")}
+	err = v.Write("app/dir/b", numbers)
+	if err != nil {
+		t.Error(err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = v.Export("app", buf, vault.ExportOptions{History: true, Format: vault.FormatJSONLines, Passphrase: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("app/a")) || bytes.Contains(buf.Bytes(), []byte("dir/b")) {
+		t.Error("archive not encrypted")
+	}
+	archive := buf.Bytes()
+
+	_, err = v.Import(bytes.NewReader(archive), vault.ImportOptions{Prefix: "restored", Passphrase: "wrong"})
+	if err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+
+	result, err := v.Import(bytes.NewReader(archive), vault.ImportOptions{Prefix: "restored", Passphrase: "test", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Imported, []string{"restored/a", "restored/dir/b"}) {
+		t.Error("unexpected result", result)
+	}
+	keys, err := v.ListRecursive("restored")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(keys) != 0 {
+		t.Error("dry run wrote secrets", keys)
+	}
+
+	result, err = v.Import(bytes.NewReader(archive), vault.ImportOptions{Prefix: "restored", Passphrase: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := v.GetSecretMetadata("restored/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CurrentVersion != 2 {
+		t.Error("history not imported", meta.CurrentVersion)
+	}
+	restored, err := v.Read("restored/dir/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored, numbers) {
+		t.Error("numbers changed by export and import", restored)
+	}
+
+	result, err = v.Import(bytes.NewReader(archive), vault.ImportOptions{Prefix: "restored", Passphrase: "test", Existing: vault.SkipExisting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 0 || len(result.Skipped) != 2 {
+		t.Error("unexpected result", result)
+	}
+	_, err = v.Import(bytes.NewReader(archive), vault.ImportOptions{Prefix: "restored", Passphrase: "test", Existing: vault.FailOnExisting})
+	if !errors.Is(err, vault.ErrAlreadyExists) {
+		t.Error("expected already exists error", err)
+	}
+
+	deleteAfter := time.Hour
+	err = v.UpdateMetadata("expiring/a", vault.MetadataSettings{DeleteVersionAfter: &deleteAfter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"expiring/a", "expiring/deleted"} {
+		for _, data := range []map[string]interface{}{{"v": "1"}, {"v": "2"}} {
+			err = v.Write(key, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	err = v.Delete("expiring/deleted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, history := range []bool{true, false} {
+		buf = &bytes.Buffer{}
+		err = v.Export("expiring", buf, vault.ExportOptions{History: history})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err = v.Import(bytes.NewReader(buf.Bytes()), vault.ImportOptions{Prefix: "expiring-restored", DryRun: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result.Imported, []string{"expiring-restored/a"}) {
+			t.Error("unexpected result", history, result)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"testing"
+	"time"
+)
+
+type boundConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func TestVaultBind(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	err = v.Write("bound", map[string]interface{}{"host": "db", "port": 5432})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	binding, err := vault.Bind[boundConfig](ctx, v, "bound",
+		vault.WithRefreshInterval[boundConfig](100*time.Millisecond),
+		vault.WithValidation(func(c boundConfig) error {
+			if c.Port <= 0 {
+				return errors.New("invalid port")
+			}
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binding.Get().Port != 5432 {
+		t.Error("unexpected value", binding.Get())
+	}
+	changed := make(chan boundConfig, 1)
+	binding.OnChange(func(old boundConfig, new boundConfig) {
+		changed <- new
+	})
+
+	err = v.Write("bound", map[string]interface{}{"host": "db", "port": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if binding.Get().Port != 5432 || binding.LastError() == nil {
+		t.Error("expected invalid update to be rejected", binding.Get(), binding.LastError())
+	}
+
+	err = v.Write("bound", map[string]interface{}{"host": "db2", "port": 5433})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changed:
+		if c.Host != "db2" || binding.Get().Host != "db2" {
+			t.Error("unexpected value", c, binding.Get())
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("missing change")
+	}
+
+	_, err = vault.Bind[boundConfig](ctx, v, "bound", vault.WithValidation(func(c boundConfig) error {
+		return errors.New("rejected")
+	}))
+	if err == nil {
+		t.Error("expected initial validation to fail")
+	}
+}
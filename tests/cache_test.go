@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"testing"
+	"time"
+)
+
+func TestVaultCache(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.New(context.Background(),
+		vault.WithAddress(conf.vaultAddress),
+		vault.WithRole("vault"),
+		vault.WithKeycloak(conf.keycloakAddress, "master", conf.keycloakClientId, conf.keycloakClientSecret),
+		vault.WithEngine("secret"),
+		vault.WithCache(vault.CacheOptions{TTL: time.Minute, MaxEntries: 2, NegativeTTL: time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	other, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	_, err = v.Read("cached")
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("expected not found", err)
+	}
+	err = other.Write("cached", map[string]interface{}{"value": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = v.Read("cached")
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("expected cached not found", err)
+	}
+
+	err = v.Write("cached", map[string]interface{}{"value": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := v.Read("cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m["value"] = "modified"
+	err = other.Write("cached", map[string]interface{}{"value": "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = v.Read("cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["value"] != "b" {
+		t.Error("expected cached value", m["value"])
+	}
+	v.InvalidateCache("cached")
+	m, err = v.Read("cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["value"] != "c" {
+		t.Error("expected value after invalidation", m["value"])
+	}
+
+	// evicts the least recently used entry
+	_, _ = v.Read("evict1")
+	_, _ = v.Read("evict2")
+	err = other.Write("cached", map[string]interface{}{"value": "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = v.Read("cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["value"] != "d" {
+		t.Error("expected evicted entry to be read again", m["value"])
+	}
+
+	err = v.Delete("cached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = v.Read("cached")
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("expected not found after delete", err)
+	}
+	err = v.Purge("cached")
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"sync"
+	"testing"
+)
+
+func TestVaultCAS(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	err = v.WriteCAS("counter", map[string]interface{}{"value": float64(0)}, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	err = v.WriteCAS("counter", map[string]interface{}{"value": float64(0)}, 0)
+	if !errors.Is(err, vault.ErrCASConflict) {
+		t.Error("expected conflict", err)
+	}
+
+	workers := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			err := v.Update("counter", func(old map[string]interface{}) (map[string]interface{}, error) {
+				value, ok := old["value"].(json.Number) // vault responses are decoded with UseNumber
+				if !ok {
+					return nil, errors.New("unexpected value type")
+				}
+				n, err := value.Int64()
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"value": n + 1}, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	workers.Wait()
+
+	m, err := v.Read("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["value"] != json.Number("5") {
+		t.Error("lost update", m["value"])
+	}
+}
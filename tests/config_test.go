@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	err := os.WriteFile(secretFile, []byte("file-secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_URL", "http://localhost:8200")
+	t.Setenv("VAULT_ROLE", "vault")
+	t.Setenv("VAULT_ENGINE", "secret")
+	t.Setenv("KEYCLOAK_URL", "http://localhost:8080")
+	t.Setenv("KEYCLOAK_REALM", "master")
+	t.Setenv("KEYCLOAK_CLIENT_ID", "client")
+	t.Setenv("KEYCLOAK_CLIENT_SECRET", "")
+	t.Setenv("KEYCLOAK_CLIENT_SECRET_FILE", secretFile)
+
+	config, err := vault.ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.KeycloakClientSecret != "file-secret" {
+		t.Error("unexpected client secret", config.KeycloakClientSecret)
+	}
+	if config.VaultUrl != "http://localhost:8200" || config.VaultEngine != "secret" {
+		t.Error("unexpected config", config)
+	}
+}
+
+func TestConfigFromFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(yamlFile, []byte("vault_url: http://localhost:8200\nvault_role: vault\nvault_engine: secret\n"+
+		"keycloak_url: http://localhost:8080\nkeycloak_realm: master\nkeycloak_client_id: client\nkeycloak_client_secret: secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := vault.ConfigFromFile(yamlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.VaultRole != "vault" || config.KeycloakClientSecret != "secret" {
+		t.Error("unexpected config", config)
+	}
+
+	jsonFile := filepath.Join(dir, "config.json")
+	err = os.WriteFile(jsonFile, []byte(`{"vault_url": "http://localhost:8200", "vault_role": "vault"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vault.ConfigFromFile(jsonFile)
+	if err == nil {
+		t.Fatal("expected error for missing values")
+	}
+	for _, missing := range []string{"vault engine", "keycloak url", "keycloak realm", "keycloak client id", "keycloak client secret"} {
+		if !strings.Contains(err.Error(), missing) {
+			t.Error("missing value not reported:", missing)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVaultCopy(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	for i, data := range []map[string]interface{}{{"v": "1"}, {"v": "2"}, {"v": "3"}} {
+		err = v.Write("src/a", data)
+		if err != nil {
+			t.Error(err)
+		}
+		if i == 0 {
+			err = v.Delete("src/a")
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	err = v.Write("src/dir/b", map[string]interface{}{"b": "b"})
+	if err != nil {
+		t.Error(err)
+	}
+	err = v.PatchMetadata("src/a", vault.MetadataSettings{CustomMetadata: map[string]string{"owner": "x"}})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = v.Copy("src/a", "copy/a", vault.CopyOptions{History: true, CustomMetadata: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := v.GetSecretMetadata("copy/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CurrentVersion != 3 || meta.Versions[1].DeletionTime == nil || meta.CustomMetadata["owner"] != "x" {
+		t.Error("unexpected metadata", meta)
+	}
+	err = v.Copy("src/a", "copy/a", vault.CopyOptions{})
+	if !errors.Is(err, vault.ErrAlreadyExists) {
+		t.Error("expected already exists error", err)
+	}
+
+	deleteAfter := time.Hour
+	err = v.UpdateMetadata("src/expiring", vault.MetadataSettings{DeleteVersionAfter: &deleteAfter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Write("src/expiring", map[string]interface{}{"e": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Move("src/expiring", "copy/expiring", vault.CopyOptions{History: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := v.Read("copy/expiring")
+	if err != nil || data["e"] != "1" {
+		t.Error("expected readable destination", data, err)
+	}
+
+	err = v.Write("src/deleted", map[string]interface{}{"d": "d"})
+	if err != nil {
+		t.Error(err)
+	}
+	err = v.Delete("src/deleted")
+	if err != nil {
+		t.Error(err)
+	}
+	err = v.MoveTree("src", "moved", vault.CopyOptions{Target: v.Engine("legacy")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := v.ListRecursive("src")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"src/deleted"}) {
+		t.Error("expected only the deleted secret to remain", keys)
+	}
+	keys, err = v.Engine("legacy").ListRecursive("moved")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"moved/a", "moved/dir/b"}) {
+		t.Error("unexpected keys", keys)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Fails all requests while offline is set
+type toggleTransport struct {
+	offline atomic.Bool
+}
+
+func (t *toggleTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.offline.Load() {
+		return nil, errors.New("offline")
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestVaultFallback(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	transport := &toggleTransport{}
+	dir := t.TempDir()
+	opts := []vault.Option{
+		vault.WithAddress(conf.vaultAddress),
+		vault.WithRole("vault"),
+		vault.WithKeycloak(conf.keycloakAddress, "master", conf.keycloakClientId, conf.keycloakClientSecret),
+		vault.WithEngine("secret"),
+		vault.WithKVVersion(2),
+		vault.WithHTTPClient(&http.Client{Transport: transport}),
+		vault.WithFallback(vault.FallbackOptions{Dir: dir, Key: make([]byte, 32)}),
+	}
+	v, err := vault.New(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	err = v.Write("fallback", map[string]interface{}{"value": "a", "number": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := v.ReadWithInfo("fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FromFallback {
+		t.Error("unexpected fallback")
+	}
+	live := result.Data
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatal("expected one fallback file", files, err)
+	}
+	persisted, err := files[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	_, err = v.Read("fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchanged, err := os.Stat(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unchanged.ModTime().Equal(persisted.ModTime()) {
+		t.Error("unchanged value was persisted again")
+	}
+
+	transport.offline.Store(true)
+	result, err = v.ReadWithInfo("fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.FromFallback || !reflect.DeepEqual(result.Data, live) {
+		t.Error("expected fallback value equal to live value", result, live)
+	}
+	_, err = v.Read("unknown")
+	if err == nil {
+		t.Error("expected error for secret without fallback value")
+	}
+
+	// starts without vault
+	offline, err := vault.New(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offline.Close()
+	if offline.State() != vault.StateDegraded {
+		t.Error("expected degraded state", offline.State())
+	}
+	m, err := offline.Read("fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["value"] != "a" {
+		t.Error("expected fallback value", m["value"])
+	}
+
+	transport.offline.Store(false)
+	err = v.Delete("fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.offline.Store(true)
+	_, err = v.Read("fallback")
+	if err == nil {
+		t.Error("expected deleted secret to be removed from fallback")
+	}
+}
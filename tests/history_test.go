@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVaultHistory(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	for _, data := range []map[string]interface{}{
+		{"a": "1", "b": "2"},
+		{"a": "1", "b": "3", "c": "4"},
+		{"c": "4"},
+	} {
+		err = v.Write("h", data)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	err = v.DestroyVersions("h", []int{1})
+	if err != nil {
+		t.Error(err)
+	}
+
+	history, err := v.History("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatal("unexpected history length", len(history))
+	}
+	if !history[0].Metadata.Destroyed || history[0].Data != nil {
+		t.Error("expected destroyed version", history[0])
+	}
+	if history[2].Metadata.Version != 3 || !reflect.DeepEqual(history[2].Data, map[string]interface{}{"c": "4"}) {
+		t.Error("unexpected version", history[2])
+	}
+
+	diff, err := v.Diff("h", 2, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := vault.SecretDiff{
+		Added:   map[string]interface{}{},
+		Removed: map[string]interface{}{"a": "1", "b": "3"},
+		Changed: map[string]vault.FieldChange{},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Error("unexpected diff", diff)
+	}
+
+	_, err = v.Rollback("h", 1, true)
+	if !errors.Is(err, vault.ErrVersionDestroyed) {
+		t.Error("expected destroyed error", err)
+	}
+	version, err := v.Rollback("h", 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 4 {
+		t.Error("unexpected version", version)
+	}
+	data, err := v.Read("h")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(data, map[string]interface{}{"a": "1", "b": "3", "c": "4"}) {
+		t.Error("unexpected data", data)
+	}
+	meta, err := v.GetSecretMetadata("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CustomMetadata[vault.RollbackSourceMetadataKey] != "2" {
+		t.Error("missing rollback source", meta.CustomMetadata)
+	}
+
+	// version 2 of r is deleted, version 3 is current
+	for i, data := range []map[string]interface{}{{"r": "1"}, {"r": "2"}, {"r": "3"}} {
+		err = v.Write("r", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			err = v.Delete("r")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	_, err = v.Rollback("r", 2, false)
+	if !errors.Is(err, vault.ErrVersionDeleted) {
+		t.Error("expected deleted error", err)
+	}
+	version, err = v.Rollback("r", 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 4 {
+		t.Error("unexpected version", version)
+	}
+	data, err = v.Read("r")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(data, map[string]interface{}{"r": "2"}) {
+		t.Error("unexpected data", data)
+	}
+	meta, err = v.GetSecretMetadata("r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Versions[2].IsDeleted() {
+		t.Error("expected version 2 to stay deleted", meta.Versions[2])
+	}
+}
+
+func TestVaultHistoryDeleteVersionAfter(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	deleteAfter := time.Hour
+	err = v.UpdateMetadata("expiring/a", vault.MetadataSettings{DeleteVersionAfter: &deleteAfter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []map[string]interface{}{{"a": "1"}, {"a": "2"}} {
+		err = v.Write("expiring/a", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := v.History("expiring/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatal("unexpected history length", len(history))
+	}
+	for i, entry := range history {
+		if entry.Metadata.DeletionTime == nil || entry.Metadata.IsDeleted() {
+			t.Error("expected future deletion time", entry.Metadata)
+		}
+		if !reflect.DeepEqual(entry.Data, map[string]interface{}{"a": strconv.Itoa(i + 1)}) {
+			t.Error("expected data of live version", entry)
+		}
+	}
+
+	version, err := v.Rollback("expiring/a", 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Error("unexpected version", version)
+	}
+	data, err := v.ReadVersion("expiring/a", 1)
+	if err != nil || data["a"] != "1" {
+		t.Error("expected source version to stay readable", data, err)
+	}
+
+	results, err := v.Find("expiring", vault.Filter{Deletion: vault.NotDeleted})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results) != 1 {
+		t.Error("expected live secret", results)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"reflect"
+	"testing"
+)
+
+func TestVaultKV1(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	if v.KVVersion() != 1 {
+		t.Error("unexpected KV version", v.KVVersion())
+	}
+
+	a := Testobj{Foo: "bar", Int: 1, List: []int{1, 2}}
+	err = v.WriteInterface("dir/a", &a)
+	if err != nil {
+		t.Error(err)
+	}
+	var aa Testobj
+	err = v.ReadInterface("dir/a", &aa)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(a, aa) {
+		t.Error("read != written")
+	}
+
+	keys, err := v.ListRecursive("")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"dir/a"}) {
+		t.Error("unexpected keys", keys)
+	}
+
+	_, err = v.ReadVersion("dir/a", 1)
+	if !errors.Is(err, vault.ErrUnsupported) {
+		t.Error("expected unsupported error", err)
+	}
+	_, err = v.GetSecretMetadata("dir/a")
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Error("expected unsupported error", err)
+	}
+
+	err = v.Delete("dir/a")
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = v.Read("dir/a")
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("expected not found error", err)
+	}
+
+	userMetadata := map[string]interface{}{"metadata": map[string]interface{}{"version": "x"}}
+	err = v.Write("user-metadata", userMetadata)
+	if err != nil {
+		t.Error(err)
+	}
+	m, err := v.Read("user-metadata")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(m, userMetadata) {
+		t.Error("unexpected data", m)
+	}
+}
+
+func TestVaultKV1Engine(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	secrets, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secrets.Close()
+
+	v := secrets.Engine("legacy")
+	if v.KVVersion() != 1 || secrets.KVVersion() != 2 {
+		t.Error("unexpected KV version", v.KVVersion(), secrets.KVVersion())
+	}
+	err = v.Close() // must not end the shared login
+	if err != nil {
+		t.Error(err)
+	}
+	if secrets.State() != vault.StateAuthenticated {
+		t.Error("unexpected state", secrets.State())
+	}
+
+	err = v.Write("engine", map[string]interface{}{"foo": "bar"})
+	if err != nil {
+		t.Error(err)
+	}
+	m, err := v.Read("engine")
+	if err != nil {
+		t.Error(err)
+	}
+	if m["foo"] != "bar" {
+		t.Error("unexpected data", m)
+	}
+	_, err = secrets.Read("engine")
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("expected engines to be separate", err)
+	}
+}
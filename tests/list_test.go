@@ -0,0 +1,115 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestVaultList(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	for _, key := range []string{"other", "tenant/a", "tenant/b/c", "tenant/b/d", "tenant/e/f"} {
+		err = v.Write(key, map[string]interface{}{"key": key})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	keys, err := v.ListKeysPrefix("tenant")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b/", "e/"}) {
+		t.Error("unexpected keys", keys)
+	}
+
+	keys, err = v.ListRecursive("tenant/")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"tenant/a", "tenant/b/c", "tenant/b/d", "tenant/e/f"}) {
+		t.Error("unexpected keys", keys)
+	}
+
+	visited := []string{}
+	err = v.Walk("", func(path string, isDir bool) error {
+		visited = append(visited, path)
+		if path == "tenant/b/" {
+			return vault.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	sort.Strings(visited)
+	if !reflect.DeepEqual(visited, []string{"other", "tenant/", "tenant/a", "tenant/b/", "tenant/e/", "tenant/e/f"}) {
+		t.Error("unexpected walk", visited)
+	}
+
+	for _, key := range []string{"tenant/a", "tenant/e/f"} {
+		err = v.PatchMetadata(key, vault.MetadataSettings{CustomMetadata: map[string]string{"owner": "x"}})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	err = v.Delete("tenant/e/f")
+	if err != nil {
+		t.Error(err)
+	}
+
+	results, err := v.Find("tenant", vault.Filter{CustomMetadata: map[string]string{"owner": "x"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results) != 2 || results[0].Path != "tenant/a" || results[1].Path != "tenant/e/f" {
+		t.Error("unexpected results", results)
+	}
+	results, err = v.Find("", vault.Filter{CustomMetadata: map[string]string{"owner": "x"}, Deletion: vault.Deleted})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results) != 1 || results[0].Path != "tenant/e/f" {
+		t.Error("unexpected results", results)
+	}
+	results, err = v.Find("", vault.Filter{UpdatedBefore: time.Now().AddDate(0, 0, -90)})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results) != 0 {
+		t.Error("unexpected results", results)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"strings"
+	"testing"
+)
+
+type populateCreds struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+type populateDatabase struct {
+	Name     string
+	Password string `vault:"populate/db#password"`
+}
+
+type populateConfig struct {
+	Env      string
+	Password string        `vault:"populate/db#password,required"`
+	Previous string        `vault:"populate/db@1#password"`
+	Port     int           `vault:"populate/db#port"`
+	PortText string        `vault:"populate/db#numeric_port"`
+	Creds    populateCreds `vault:"populate/db"`
+	Timeout  int           `vault:"populate/missing#timeout,default=30"`
+	Optional string        `vault:"populate/missing#optional"`
+	Nested   struct {
+		Password string `vault:"populate/db#password"`
+	}
+	Databases []populateDatabase
+}
+
+type populateMissing struct {
+	A string `vault:"populate/missing#a,required"`
+	B string `vault:"populate/db#b,required"`
+}
+
+func TestVaultPopulate(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	err = v.Write("populate/db", map[string]interface{}{"user": "admin", "password": "old", "port": "5432"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Write("populate/db", map[string]interface{}{"user": "admin", "password": "new", "port": "5432", "numeric_port": 5432})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := populateConfig{Env: "prod", Optional: "unchanged", Databases: []populateDatabase{{Name: "a"}, {Name: "b"}}}
+	err = vault.Populate(context.Background(), v, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Env != "prod" || cfg.Password != "new" || cfg.Previous != "old" || cfg.Port != 5432 || cfg.PortText != "5432" || cfg.Timeout != 30 || cfg.Optional != "unchanged" {
+		t.Error("unexpected config", cfg)
+	}
+	if cfg.Creds.User != "admin" || cfg.Nested.Password != "new" || cfg.Databases[1].Password != "new" {
+		t.Error("unexpected nested config", cfg)
+	}
+
+	err = vault.Populate(context.Background(), v, &populateMissing{})
+	if err == nil || !strings.Contains(err.Error(), "populate/missing#a") || !strings.Contains(err.Error(), "populate/db#b") {
+		t.Error("expected all missing secrets to be reported", err)
+	}
+}
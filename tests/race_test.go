@@ -0,0 +1,89 @@
+//go:build race
+
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with go test -race. The short token lifetime of the role forces renewals and logins of the token watcher,
+// while Renew and Relogin are called concurrently.
+func TestVaultTokenRace(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault-short", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	ctx, stop := context.WithTimeout(context.Background(), 30*time.Second)
+	defer stop()
+	workers := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		key := "race" + strconv.Itoa(i)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for ctx.Err() == nil {
+				err := v.Write(key, map[string]interface{}{"time": time.Now().String()})
+				if err != nil {
+					t.Error(err)
+				}
+				_, err = v.Read(key)
+				if err != nil {
+					t.Error(err)
+				}
+				_ = v.State()
+			}
+		}()
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		for ctx.Err() == nil {
+			err := v.Renew(ctx)
+			if err != nil && ctx.Err() == nil {
+				t.Error(err)
+			}
+			err = v.Relogin(ctx)
+			if err != nil && ctx.Err() == nil {
+				t.Error(err)
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}()
+	workers.Wait()
+
+	if v.State() != vault.StateAuthenticated {
+		t.Error("unexpected state", v.State(), v.LastError())
+	}
+}
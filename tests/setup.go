@@ -101,11 +101,53 @@ func setup() (ctx context.Context, cancel context.CancelFunc, wg *sync.WaitGroup
 		return
 	}
 
+	// setup vault role with short token lifetime to force frequent renewals and logins
+	req = client.NewRequest(http.MethodPost, "/v1/auth/jwt/role/vault-short")
+	req.BodyBytes, err = json.Marshal(map[string]interface{}{
+		"role_type":  "jwt",
+		"user_claim": "sub",
+		"bound_claims": map[string]interface{}{
+			"roles": []string{"vault"},
+		},
+		"token_ttl":     3,
+		"token_max_ttl": 9,
+	})
+	resp, err = performRequest(client, req)
+	if err != nil {
+		return
+	}
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp.StatusCode > 299 {
+		err = errors.New("unexpected status code " + strconv.Itoa(resp.StatusCode))
+		return
+	}
+
+	// setup KV version 1 engine
+	req = client.NewRequest(http.MethodPost, "/v1/sys/mounts/legacy")
+	req.BodyBytes, err = json.Marshal(map[string]interface{}{
+		"type":    "kv",
+		"options": map[string]interface{}{"version": "1"},
+	})
+	resp, err = performRequest(client, req)
+	if err != nil {
+		return
+	}
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp.StatusCode > 299 {
+		err = errors.New("unexpected status code " + strconv.Itoa(resp.StatusCode))
+		return
+	}
+
 	// setup policy
 	req = client.NewRequest(http.MethodPut, "/v1/sys/policies/acl/test")
 	req.BodyBytes, err = json.Marshal(map[string]interface{}{
-		"name":   "test",
-		"policy": "path \"secret/*\" {\n  capabilities = [\"create\", \"read\", \"update\", \"delete\", \"list\"]\n}",
+		"name": "test",
+		"policy": "path \"secret/*\" {\n  capabilities = [\"create\", \"read\", \"update\", \"patch\", \"delete\", \"list\"]\n}\n" +
+			"path \"legacy/*\" {\n  capabilities = [\"create\", \"read\", \"update\", \"delete\", \"list\"]\n}",
 	})
 	resp, err = performRequest(client, req)
 	if err != nil {
@@ -18,9 +18,12 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"github.com/SENERGY-Platform/vault-jwt-go/vault"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type Testobj struct {
@@ -84,6 +87,18 @@ func TestVault(t *testing.T) {
 		t.Error("read != written")
 	}
 
+	typed, err := vault.Get[Testobj](v, "a", vault.DisallowUnknownFields())
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(a, typed) {
+		t.Error("read != written")
+	}
+	_, err = vault.Get[struct{ Foo string }](v, "a", vault.DisallowUnknownFields())
+	if err == nil {
+		t.Error("strict decoding ignored unknown fields")
+	}
+
 	b := map[string]interface{}{"b": float64(127)}
 	err = v.Write("b", b)
 	if err != nil {
@@ -99,6 +114,33 @@ func TestVault(t *testing.T) {
 		t.Error("read != written")
 	}
 
+	err = v.Patch("b", map[string]interface{}{"c": "d"})
+	if err != nil {
+		t.Error(err)
+	}
+	bb, err = v.Read("b")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(map[string]interface{}{"b": json.Number("127"), "c": "d"}, bb) { // vault responses are decoded with UseNumber
+		t.Error("unexpected patch result", bb)
+	}
+	err = v.Patch("b", map[string]interface{}{"c": nil})
+	if err != nil {
+		t.Error(err)
+	}
+	bb, err = v.Read("b")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(map[string]interface{}{"b": json.Number("127")}, bb) {
+		t.Error("expected patch to remove c", bb)
+	}
+	err = v.Patch("missing", map[string]interface{}{"c": "d"})
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("expected not found error", err)
+	}
+
 	keys, err = v.ListKeys()
 	if err != nil {
 		t.Error(err)
@@ -116,8 +158,8 @@ func TestVault(t *testing.T) {
 	}
 
 	err = v.ReadInterface("a", &aa)
-	if err == nil {
-		t.Error("could read deleted key")
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("could read deleted key", err)
 	}
 	err = nil
 
@@ -140,6 +182,38 @@ func TestVault(t *testing.T) {
 		t.Error("expected delete timestamp")
 	}
 
+	secretMeta, err := v.GetSecretMetadata("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secretMeta.CurrentVersion != 1 || len(secretMeta.Versions) != 1 {
+		t.Error("unexpected versions", secretMeta.CurrentVersion, secretMeta.Versions)
+	}
+	if current, ok := secretMeta.Current(); !ok || current.DeletionTime == nil {
+		t.Error("expected delete timestamp")
+	}
+	_, err = v.GetSecretMetadata("missing")
+	if !errors.Is(err, vault.ErrNotFound) {
+		t.Error("expected not found error", err)
+	}
+
+	maxVersions := 3
+	err = v.UpdateMetadata("b", vault.MetadataSettings{MaxVersions: &maxVersions, CustomMetadata: map[string]string{"owner": "test", "service": "foo"}})
+	if err != nil {
+		t.Error(err)
+	}
+	err = v.PatchMetadata("b", vault.MetadataSettings{CustomMetadata: map[string]string{"rotated": "never"}}, "service")
+	if err != nil {
+		t.Error(err)
+	}
+	secretMeta, err = v.GetSecretMetadata("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secretMeta.MaxVersions != 3 || !reflect.DeepEqual(secretMeta.CustomMetadata, map[string]string{"owner": "test", "rotated": "never"}) {
+		t.Error("unexpected metadata", secretMeta.MaxVersions, secretMeta.CustomMetadata)
+	}
+
 	err = v.Undelete("a", []int{1})
 	if err != nil {
 		t.Error(err)
@@ -168,3 +242,49 @@ func TestVault(t *testing.T) {
 		t.Error("key list incorrect content")
 	}
 }
+
+func TestVaultClose(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.New(context.Background(),
+		vault.WithAddress(conf.vaultAddress),
+		vault.WithRole("vault"),
+		vault.WithKeycloak(conf.keycloakAddress, "master", conf.keycloakClientId, conf.keycloakClientSecret),
+		vault.WithEngine("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.Write("a", map[string]interface{}{"a": "b"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- v.CloseAndRevoke(context.Background())
+	}()
+	select {
+	case err = <-closed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("close did not return")
+	}
+
+	_, err = v.Read("a")
+	if err == nil {
+		t.Error("could read with revoked token")
+	}
+
+	err = v.Close() // closing twice must not block
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault"
+	"testing"
+	"time"
+)
+
+func TestVaultWatch(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	changes := v.Watch(ctx, "watched/a", 100*time.Millisecond)
+	prefixChanges := v.WatchPrefix(ctx, "watched", 100*time.Millisecond)
+	time.Sleep(500 * time.Millisecond) // first poll
+
+	expect := func(changes <-chan vault.Change, changeType vault.ChangeType, oldValue interface{}, newValue interface{}) {
+		t.Helper()
+		select {
+		case change := <-changes:
+			if change.Key != "watched/a" || change.Type != changeType || change.OldData["value"] != oldValue || change.NewData["value"] != newValue {
+				t.Error("unexpected change", change)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("missing change", changeType)
+		}
+	}
+
+	err = v.Write("watched/a", map[string]interface{}{"value": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(changes, vault.SecretCreated, nil, "1")
+	expect(prefixChanges, vault.SecretCreated, nil, "1")
+
+	err = v.Write("watched/a", map[string]interface{}{"value": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(changes, vault.SecretUpdated, "1", "2")
+	expect(prefixChanges, vault.SecretUpdated, "1", "2")
+
+	err = v.Delete("watched/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(changes, vault.SecretDeleted, "2", nil)
+	expect(prefixChanges, vault.SecretDeleted, "2", nil)
+
+	err = v.Purge("watched/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(changes, vault.SecretPurged, nil, nil)
+	expect(prefixChanges, vault.SecretPurged, nil, nil)
+
+	stop()
+	for range changes {
+	}
+}
+
+func TestVaultWatchKV1(t *testing.T) {
+	_, cancel, wg, conf, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer wg.Wait()
+	defer cancel()
+
+	v, err := vault.NewVault(context.Background(), conf.vaultAddress,
+		"vault", conf.keycloakAddress,
+		"master", conf.keycloakClientId, conf.keycloakClientSecret, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	changes := v.Watch(ctx, "watched/a", 100*time.Millisecond)
+	prefixChanges := v.WatchPrefix(ctx, "watched", 100*time.Millisecond)
+	time.Sleep(500 * time.Millisecond) // first poll
+
+	expect := func(changes <-chan vault.Change, changeType vault.ChangeType, oldValue interface{}, newValue interface{}) {
+		t.Helper()
+		select {
+		case change := <-changes:
+			if change.Key != "watched/a" || change.Type != changeType || change.Version != 0 || change.OldData["value"] != oldValue || change.NewData["value"] != newValue {
+				t.Error("unexpected change", change)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("missing change", changeType)
+		}
+	}
+
+	err = v.Write("watched/a", map[string]interface{}{"value": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(changes, vault.SecretCreated, nil, "1")
+	expect(prefixChanges, vault.SecretCreated, nil, "1")
+
+	err = v.Write("watched/a", map[string]interface{}{"value": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(changes, vault.SecretUpdated, "1", "2")
+	expect(prefixChanges, vault.SecretUpdated, "1", "2")
+
+	err = v.Delete("watched/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(changes, vault.SecretDeleted, "2", nil)
+	expect(prefixChanges, vault.SecretDeleted, "2", nil)
+
+	stop()
+	for range changes {
+	}
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	archiveFormat          = "vault-jwt-go/archive"
+	encryptedArchiveFormat = "vault-jwt-go/encrypted-archive"
+	archiveVersion         = 1
+)
+
+type ArchiveFormat int
+
+const (
+	FormatJSON      ArchiveFormat = iota // a single JSON document
+	FormatJSONLines                      // a header line followed by one line per secret
+)
+
+// Options of Export. Secrets whose current version is deleted or destroyed are skipped with and without History.
+type ExportOptions struct {
+	History    bool // exports all readable versions instead of the current version only, deleted and destroyed versions are skipped
+	Format     ArchiveFormat
+	Passphrase string // encrypts the archive if set
+}
+
+// Controls how Import handles secrets that already exist
+type ExistingPolicy int
+
+const (
+	SkipExisting ExistingPolicy = iota
+	OverwriteExisting
+	FailOnExisting // fails before anything is written if any secret exists
+)
+
+type ImportOptions struct {
+	Prefix     string // prepended to the paths of the archive
+	DryRun     bool   // only reports what would be imported
+	Existing   ExistingPolicy
+	Passphrase string // required for encrypted archives
+}
+
+type ImportResult struct {
+	Imported []string
+	Skipped  []string
+}
+
+type archive struct {
+	Format  string          `json:"format"`
+	Version int             `json:"version"`
+	Engine  string          `json:"engine,omitempty"`
+	Prefix  string          `json:"prefix,omitempty"`
+	Created time.Time       `json:"created,omitempty"`
+	Secrets []archiveSecret `json:"secrets,omitempty"`
+
+	// set for encrypted archives
+	Salt       []byte `json:"salt,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+type archiveSecret struct {
+	Path           string                   `json:"path"`
+	Versions       []map[string]interface{} `json:"versions"` // oldest first
+	CustomMetadata map[string]string        `json:"custom_metadata,omitempty"`
+}
+
+// Writes all secrets below the prefix to w. Paths in the archive are relative to the prefix.
+func (vault *Vault) Export(prefix string, w io.Writer, opts ExportOptions) error {
+	return vault.ExportCtx(context.Background(), prefix, w, opts)
+}
+
+// Same as Export, but the requests are bound to the provided context
+func (vault *Vault) ExportCtx(ctx context.Context, prefix string, w io.Writer, opts ExportOptions) error {
+	if opts.History {
+		if err := vault.requireVersioning(); err != nil {
+			return err
+		}
+	}
+	prefix = normalizePrefix(prefix)
+	keys, err := vault.ListRecursiveCtx(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	out := w
+	buf := &bytes.Buffer{}
+	if opts.Passphrase != "" {
+		out = buf
+	}
+	header := archive{Format: archiveFormat, Version: archiveVersion, Engine: vault.vaultEngine, Prefix: prefix, Created: time.Now()}
+	encoder := json.NewEncoder(out)
+	if opts.Format == FormatJSONLines {
+		err = encoder.Encode(header)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		secret, err := vault.exportSecret(ctx, key, opts.History)
+		if errors.Is(err, ErrNotFound) { // current version deleted or purged since listing
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		secret.Path = strings.TrimPrefix(key, prefix)
+		if opts.Format == FormatJSONLines {
+			err = encoder.Encode(secret)
+			if err != nil {
+				return err
+			}
+		} else {
+			header.Secrets = append(header.Secrets, secret)
+		}
+	}
+	if opts.Format != FormatJSONLines {
+		err = encoder.Encode(header)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Passphrase == "" {
+		return nil
+	}
+	salt, err := newSalt()
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(opts.Passphrase, salt)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := encrypt(key, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(archive{Format: encryptedArchiveFormat, Version: archiveVersion, Salt: salt, Ciphertext: ciphertext})
+}
+
+func (vault *Vault) exportSecret(ctx context.Context, key string, history bool) (secret archiveSecret, err error) {
+	if !history {
+		data, _, err := vault.readSecret(ctx, key, 0)
+		if err != nil {
+			return secret, err
+		}
+		secret.Versions = []map[string]interface{}{data}
+		if vault.kvVersion != 1 {
+			meta, err := vault.GetSecretMetadataCtx(ctx, key)
+			if err != nil {
+				return secret, err
+			}
+			secret.CustomMetadata = meta.CustomMetadata
+		}
+		return secret, nil
+	}
+	meta, err := vault.GetSecretMetadataCtx(ctx, key)
+	if err != nil {
+		return secret, err
+	}
+	if meta.DeletionState() != NotDeleted { // skipped like without history
+		return secret, ErrNotFound
+	}
+	versions, err := vault.HistoryCtx(ctx, key)
+	if err != nil {
+		return secret, err
+	}
+	for _, version := range versions {
+		if version.Data != nil {
+			secret.Versions = append(secret.Versions, version.Data)
+		}
+	}
+	if len(secret.Versions) == 0 {
+		return secret, errors.New("no readable version of " + key)
+	}
+	secret.CustomMetadata = meta.CustomMetadata
+	return secret, nil
+}
+
+// Writes the secrets of an archive created by Export. All versions of a secret are written in order.
+func (vault *Vault) Import(r io.Reader, opts ImportOptions) (ImportResult, error) {
+	return vault.ImportCtx(context.Background(), r, opts)
+}
+
+// Same as Import, but the requests are bound to the provided context
+func (vault *Vault) ImportCtx(ctx context.Context, r io.Reader, opts ImportOptions) (result ImportResult, err error) {
+	secrets, err := readArchive(r, opts.Passphrase)
+	if err != nil {
+		return result, err
+	}
+	prefix := normalizePrefix(opts.Prefix)
+	existing := map[string]bool{}
+	for _, secret := range secrets {
+		path := prefix + secret.Path
+		existing[path], err = vault.exists(ctx, path)
+		if err != nil {
+			return result, err
+		}
+		if existing[path] && opts.Existing == FailOnExisting {
+			return result, errors.Join(errors.New("unable to import "+path), ErrAlreadyExists)
+		}
+	}
+	for _, secret := range secrets {
+		path := prefix + secret.Path
+		if existing[path] && opts.Existing == SkipExisting {
+			result.Skipped = append(result.Skipped, path)
+			continue
+		}
+		if !opts.DryRun {
+			err = vault.importSecret(ctx, path, secret)
+			if err != nil {
+				return result, errors.Join(errors.New("unable to import "+path), err)
+			}
+		}
+		result.Imported = append(result.Imported, path)
+	}
+	return result, nil
+}
+
+func (vault *Vault) importSecret(ctx context.Context, path string, secret archiveSecret) error {
+	versions := secret.Versions
+	if vault.kvVersion == 1 && len(versions) > 0 {
+		versions = versions[len(versions)-1:]
+	}
+	for _, data := range versions {
+		err := vault.WriteCtx(ctx, path, data)
+		if err != nil {
+			return err
+		}
+	}
+	if len(secret.CustomMetadata) > 0 && vault.kvVersion != 1 {
+		return vault.PatchMetadataCtx(ctx, path, MetadataSettings{CustomMetadata: secret.CustomMetadata})
+	}
+	return nil
+}
+
+// Reads plain or encrypted archives in both formats
+func readArchive(r io.Reader, passphrase string) ([]archiveSecret, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber() // keeps large and integer numbers exact like vault reads
+	header := archive{}
+	err := decoder.Decode(&header)
+	if err != nil {
+		return nil, err
+	}
+	if header.Format == encryptedArchiveFormat {
+		if passphrase == "" {
+			return nil, errors.New("archive is encrypted, passphrase required")
+		}
+		key, err := deriveKey(passphrase, header.Salt)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := decrypt(key, header.Ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		return readArchive(bytes.NewReader(plaintext), "")
+	}
+	if header.Format != archiveFormat {
+		return nil, errors.New("unknown archive format " + header.Format)
+	}
+	if header.Version != archiveVersion {
+		return nil, errors.New("unsupported archive version " + strconv.Itoa(header.Version))
+	}
+	secrets := header.Secrets
+	for decoder.More() {
+		secret := archiveSecret{}
+		err = decoder.Decode(&secret)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
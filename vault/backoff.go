@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Controls how often a failed re-authentication is retried by the background token renewal
+type BackoffPolicy struct {
+	InitialDelay time.Duration // delay after the first failed attempt, 0 uses the InitialDelay of DefaultBackoffPolicy
+	MaxDelay     time.Duration // upper bound of the delay between two attempts, 0 is unbounded
+	Jitter       float64       // fraction (0 to 1) by which each delay is randomly shortened
+	MaxAttempts  int           // number of attempts before giving up, 0 retries forever
+}
+
+var DefaultBackoffPolicy = BackoffPolicy{
+	InitialDelay: time.Second,
+	MaxDelay:     time.Minute,
+	Jitter:       0.2,
+	MaxAttempts:  0,
+}
+
+// Returns the delay to wait after the specified (1-based) failed attempt
+func (policy BackoffPolicy) delay(attempt int) time.Duration {
+	d := policy.InitialDelay
+	if d <= 0 {
+		d = DefaultBackoffPolicy.InitialDelay
+	}
+	for i := 1; i < attempt && d <= math.MaxInt64/2; i++ {
+		if policy.MaxDelay > 0 && d >= policy.MaxDelay {
+			break
+		}
+		d *= 2
+	}
+	if policy.MaxDelay > 0 && d > policy.MaxDelay {
+		d = policy.MaxDelay
+	}
+	jitter := math.Min(policy.Jitter, 1)
+	if jitter > 0 {
+		d -= time.Duration(rand.Float64() * jitter * float64(d))
+	}
+	return d
+}
+
+// Describes the authentication state of a Vault
+type State int
+
+const (
+	StateAuthenticated State = iota // the login token is valid and renewed in the background
+	StateDegraded                   // re-authentication failed and is being retried
+	StateFailed                     // re-authentication failed MaxAttempts times, the token is no longer renewed until Relogin succeeds
+	StateClosed                     // the vault was closed
+)
+
+func (state State) String() string {
+	switch state {
+	case StateAuthenticated:
+		return "authenticated"
+	case StateDegraded:
+		return "degraded"
+	case StateFailed:
+		return "failed"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
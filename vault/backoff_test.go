@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  BackoffPolicy
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"first attempt", BackoffPolicy{InitialDelay: time.Second, MaxDelay: time.Minute}, 1, time.Second, time.Second},
+		{"growth", BackoffPolicy{InitialDelay: time.Second, MaxDelay: time.Minute}, 4, 8 * time.Second, 8 * time.Second},
+		{"cap", BackoffPolicy{InitialDelay: time.Second, MaxDelay: time.Minute}, 10, time.Minute, time.Minute},
+		{"cap below initial delay", BackoffPolicy{InitialDelay: time.Minute, MaxDelay: time.Second}, 1, time.Second, time.Second},
+		{"unbounded", BackoffPolicy{InitialDelay: time.Second}, 10, 512 * time.Second, 512 * time.Second},
+		{"unbounded overflow", BackoffPolicy{InitialDelay: time.Second}, 1000, time.Duration(math.MaxInt64 / 2), time.Duration(math.MaxInt64)},
+		{"zero initial delay", BackoffPolicy{MaxAttempts: 3}, 1, DefaultBackoffPolicy.InitialDelay, DefaultBackoffPolicy.InitialDelay},
+		{"zero initial delay growth", BackoffPolicy{MaxAttempts: 3}, 3, 4 * DefaultBackoffPolicy.InitialDelay, 4 * DefaultBackoffPolicy.InitialDelay},
+		{"jitter", BackoffPolicy{InitialDelay: 10 * time.Second, MaxDelay: time.Minute, Jitter: 0.2}, 1, 8 * time.Second, 10 * time.Second},
+		{"jitter above 1", BackoffPolicy{InitialDelay: 10 * time.Second, Jitter: 2}, 1, 0, 10 * time.Second},
+		{"negative jitter", BackoffPolicy{InitialDelay: 10 * time.Second, Jitter: -1}, 1, 10 * time.Second, 10 * time.Second},
+		{"default policy", DefaultBackoffPolicy, 2, 1600 * time.Millisecond, 2 * time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := test.policy.delay(test.attempt)
+				if d < test.min || d > test.max {
+					t.Fatal("delay", d, "not in", test.min, test.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Keeps the decoded value of a secret up to date, see Bind
+type Binding[T any] struct {
+	key           string
+	value         atomic.Pointer[T]
+	interval      time.Duration
+	validate      func(T) error
+	decodeOptions []DecodeOption
+	mux           sync.Mutex // guards callbacks and lastErr
+	callbacks     []func(old T, new T)
+	lastErr       error
+}
+
+// Configures a Binding created with Bind
+type BindOption[T any] func(*Binding[T])
+
+// Sets how often the secret is checked for changes. Defaults to DefaultWatchInterval.
+func WithRefreshInterval[T any](interval time.Duration) BindOption[T] {
+	return func(binding *Binding[T]) {
+		binding.interval = interval
+	}
+}
+
+// Validates every value before it is used. Rejected updates are logged and the previous value is kept.
+func WithValidation[T any](validate func(T) error) BindOption[T] {
+	return func(binding *Binding[T]) {
+		binding.validate = validate
+	}
+}
+
+// Sets the options used to decode the secret
+func WithBindDecodeOptions[T any](opts ...DecodeOption) BindOption[T] {
+	return func(binding *Binding[T]) {
+		binding.decodeOptions = opts
+	}
+}
+
+// Reads the secret with the specified key, decodes it into a value of type T and refreshes the value in the background
+// until ctx is done. Returns an error if the initial value can not be read, decoded or validated. Updates that can not be
+// decoded or validated and deletions of the secret are logged, but the previous value is kept.
+func Bind[T any](ctx context.Context, v *Vault, key string, opts ...BindOption[T]) (*Binding[T], error) {
+	binding := &Binding[T]{key: key, interval: DefaultWatchInterval}
+	for _, opt := range opts {
+		opt(binding)
+	}
+	result, err := v.ReadWithInfoCtx(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := binding.check(result.Data)
+	if err != nil {
+		return nil, err
+	}
+	binding.value.Store(&value)
+	initial := map[string]watchState{key: {live: true, data: result.Data}}
+	changes := v.watch(ctx, binding.interval, initial, v.keyPoller(key))
+	go func() {
+		for change := range changes {
+			err := binding.update(change)
+			binding.mux.Lock()
+			binding.lastErr = err
+			binding.mux.Unlock()
+			if err != nil {
+				v.logger.Println("WARN: [VAULT] Keeping previous value of " + key + ": " + err.Error())
+			}
+		}
+	}()
+	return binding, nil
+}
+
+// Returns the latest valid value
+func (binding *Binding[T]) Get() T {
+	return *binding.value.Load()
+}
+
+// Registers a callback that is called after the value was replaced. Callbacks are called sequentially in the background.
+func (binding *Binding[T]) OnChange(fn func(old T, new T)) {
+	binding.mux.Lock()
+	defer binding.mux.Unlock()
+	binding.callbacks = append(binding.callbacks, fn)
+}
+
+// Returns the reason the last update was rejected or nil if it was applied
+func (binding *Binding[T]) LastError() error {
+	binding.mux.Lock()
+	defer binding.mux.Unlock()
+	return binding.lastErr
+}
+
+func (binding *Binding[T]) update(change Change) error {
+	if change.NewData == nil {
+		return errors.New("secret " + change.Type.String())
+	}
+	if reflect.DeepEqual(change.OldData, change.NewData) {
+		return nil
+	}
+	value, err := binding.check(change.NewData)
+	if err != nil {
+		return err
+	}
+	old := binding.value.Swap(&value)
+	binding.mux.Lock()
+	callbacks := append([]func(old T, new T){}, binding.callbacks...)
+	binding.mux.Unlock()
+	for _, fn := range callbacks {
+		fn(*old, value)
+	}
+	return nil
+}
+
+// Decodes and validates the data
+func (binding *Binding[T]) check(data map[string]interface{}) (value T, err error) {
+	value, err = decode[T](data, binding.decodeOptions...)
+	if err != nil {
+		return value, err
+	}
+	if binding.validate != nil {
+		err = binding.validate(value)
+	}
+	return value, err
+}
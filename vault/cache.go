@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type CacheOptions struct {
+	TTL         time.Duration                  // how long read secrets are cached
+	TTLFunc     func(key string) time.Duration // overrides TTL per key if set, secrets with a TTL <= 0 are not cached
+	MaxEntries  int                            // least recently used entries are evicted if exceeded, 0 means unlimited
+	NegativeTTL time.Duration                  // how long ErrNotFound is cached, 0 disables negative caching
+}
+
+// Caches the results of Read and everything based on it, e.g. ReadInterface and Get. Cached secrets are invalidated by
+// writes, deletes and purges through the Vault and all handles created with Engine. Changes by other clients are visible
+// after the TTL expired.
+func WithCache(cacheOptions CacheOptions) Option {
+	return func(o *options) {
+		o.cache = &cacheOptions
+	}
+}
+
+// Removes the secret with the specified key from the cache, e.g. after it was changed by another client
+func (vault *Vault) InvalidateCache(key string) {
+	if vault.cache != nil {
+		vault.cache.invalidate(vault.path("data", key))
+	}
+}
+
+// Removes all secrets from the cache
+func (vault *Vault) ClearCache() {
+	if vault.cache != nil {
+		vault.cache.clear()
+	}
+}
+
+type readCache struct {
+	options    CacheOptions
+	mux        sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List // front is the most recently used entry
+	generation uint64     // incremented on every invalidation
+}
+
+type cacheEntry struct {
+	path    string
+	data    map[string]interface{}
+	err     error
+	expires time.Time
+}
+
+func newReadCache(options CacheOptions) *readCache {
+	return &readCache{
+		options: options,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Returns a copy of the cached data or the cached error. The generation is required to store the result of a read with put.
+func (cache *readCache) get(path string) (data map[string]interface{}, err error, generation uint64, ok bool) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+	element, ok := cache.entries[path]
+	if !ok {
+		return nil, nil, cache.generation, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		cache.remove(element)
+		return nil, nil, cache.generation, false
+	}
+	cache.lru.MoveToFront(element)
+	return copyData(entry.data), entry.err, cache.generation, true
+}
+
+// Stores the result of a read, unless the cache was invalidated since the generation was obtained
+func (cache *readCache) put(path string, key string, data map[string]interface{}, err error, generation uint64) {
+	ttl := cache.options.TTL
+	if cache.options.TTLFunc != nil {
+		ttl = cache.options.TTLFunc(key)
+	}
+	if err != nil {
+		ttl = cache.options.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+	if generation != cache.generation {
+		return
+	}
+	if element, ok := cache.entries[path]; ok {
+		cache.remove(element)
+	}
+	cache.entries[path] = cache.lru.PushFront(&cacheEntry{path: path, data: copyData(data), err: err, expires: time.Now().Add(ttl)})
+	for cache.options.MaxEntries > 0 && cache.lru.Len() > cache.options.MaxEntries {
+		cache.remove(cache.lru.Back())
+	}
+}
+
+func (cache *readCache) invalidate(path string) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+	cache.generation++
+	if element, ok := cache.entries[path]; ok {
+		cache.remove(element)
+	}
+}
+
+func (cache *readCache) clear() {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+	cache.generation++
+	cache.entries = map[string]*list.Element{}
+	cache.lru.Init()
+}
+
+func (cache *readCache) remove(element *list.Element) {
+	cache.lru.Remove(element)
+	delete(cache.entries, element.Value.(*cacheEntry).path)
+}
+
+// Deep copies secret data, so callers can not modify cached values
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		result[k] = copyValue(v)
+	}
+	return result
+}
+
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyData(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = copyValue(v[i])
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+const DefaultCASRetries = 5
+
+// Writes the data as a secret with the specified key, if the current version of the secret equals expectedVersion.
+// Use 0 to only write the secret if it does not exist yet. Returns an error matching ErrCASConflict if the versions differ.
+func (vault *Vault) WriteCAS(key string, data map[string]interface{}, expectedVersion int) error {
+	return vault.WriteCASCtx(context.Background(), key, data, expectedVersion)
+}
+
+// Same as WriteCAS, but the request is bound to the provided context
+func (vault *Vault) WriteCASCtx(ctx context.Context, key string, data map[string]interface{}, expectedVersion int) error {
+	_, err := vault.writeSecret(ctx, key, data, &expectedVersion)
+	return err
+}
+
+// Reads the secret with the specified key, passes it to update and writes the result with check-and-set.
+// The secret is nil if it does not exist. If another client changed the secret in the meantime, the update is retried
+// (see WithCASRetries). Returns a *ConflictError if all attempts failed.
+func (vault *Vault) Update(key string, update func(old map[string]interface{}) (map[string]interface{}, error)) error {
+	return vault.UpdateCtx(context.Background(), key, update)
+}
+
+// Same as Update, but the requests are bound to the provided context
+func (vault *Vault) UpdateCtx(ctx context.Context, key string, update func(old map[string]interface{}) (map[string]interface{}, error)) error {
+	attempts := vault.casRetries + 1
+	for i := 0; i < attempts; i++ {
+		old, meta, err := vault.readSecret(ctx, key, 0)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		version := 0
+		if meta != nil {
+			version = meta.Version
+		}
+		data, err := update(old)
+		if err != nil {
+			return err
+		}
+		_, err = vault.writeSecret(ctx, key, data, &version)
+		if err == nil || !errors.Is(err, ErrCASConflict) {
+			return err
+		}
+	}
+	return &ConflictError{Key: key, Attempts: attempts}
+}
+
+// Returned by Update if the secret was changed concurrently on every attempt. Matches ErrCASConflict with errors.Is.
+type ConflictError struct {
+	Key      string
+	Attempts int
+}
+
+func (e *ConflictError) Error() string {
+	return "unable to update " + e.Key + " after " + strconv.Itoa(e.Attempts) + " attempts: " + ErrCASConflict.Error()
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrCASConflict
+}
+
+// Sets how often Update retries after a check-and-set conflict. Defaults to DefaultCASRetries.
+func WithCASRetries(retries int) Option {
+	return func(o *options) {
+		o.casRetries = retries
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Connection settings of a Vault. Can be loaded with ConfigFromEnv or ConfigFromFile and passed to New with WithConfig.
+type Config struct {
+	VaultUrl                 string `json:"vault_url" yaml:"vault_url"`
+	VaultRole                string `json:"vault_role" yaml:"vault_role"`
+	VaultEngine              string `json:"vault_engine" yaml:"vault_engine"`
+	KeycloakUrl              string `json:"keycloak_url" yaml:"keycloak_url"`
+	KeycloakRealm            string `json:"keycloak_realm" yaml:"keycloak_realm"`
+	KeycloakClientId         string `json:"keycloak_client_id" yaml:"keycloak_client_id"`
+	KeycloakClientSecret     string `json:"keycloak_client_secret" yaml:"keycloak_client_secret"`
+	KeycloakClientSecretFile string `json:"keycloak_client_secret_file" yaml:"keycloak_client_secret_file"` // used if KeycloakClientSecret is empty, e.g. for docker or kubernetes secrets
+}
+
+// Loads the config from the environment variables VAULT_URL, VAULT_ROLE, VAULT_ENGINE, KEYCLOAK_URL, KEYCLOAK_REALM,
+// KEYCLOAK_CLIENT_ID and KEYCLOAK_CLIENT_SECRET or KEYCLOAK_CLIENT_SECRET_FILE. Returns an error listing all missing values.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		VaultUrl:                 os.Getenv("VAULT_URL"),
+		VaultRole:                os.Getenv("VAULT_ROLE"),
+		VaultEngine:              os.Getenv("VAULT_ENGINE"),
+		KeycloakUrl:              os.Getenv("KEYCLOAK_URL"),
+		KeycloakRealm:            os.Getenv("KEYCLOAK_REALM"),
+		KeycloakClientId:         os.Getenv("KEYCLOAK_CLIENT_ID"),
+		KeycloakClientSecret:     os.Getenv("KEYCLOAK_CLIENT_SECRET"),
+		KeycloakClientSecretFile: os.Getenv("KEYCLOAK_CLIENT_SECRET_FILE"),
+	}
+	return config, config.load()
+}
+
+// Loads the config from a JSON or YAML file (by extension .yaml or .yml). Returns an error listing all missing values.
+func ConfigFromFile(path string) (config Config, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &config)
+	default:
+		err = json.Unmarshal(b, &config)
+	}
+	if err != nil {
+		return config, err
+	}
+	return config, config.load()
+}
+
+// Checks that all required values are present. The returned error lists all missing values.
+func (config Config) Validate() error {
+	errs := []error{}
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"vault url", config.VaultUrl},
+		{"vault role", config.VaultRole},
+		{"vault engine", config.VaultEngine},
+		{"keycloak url", config.KeycloakUrl},
+		{"keycloak realm", config.KeycloakRealm},
+		{"keycloak client id", config.KeycloakClientId},
+		{"keycloak client secret", config.KeycloakClientSecret},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			errs = append(errs, errors.New("missing "+r.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reads the client secret file if necessary and validates the config
+func (config *Config) load() error {
+	if config.KeycloakClientSecret == "" && config.KeycloakClientSecretFile != "" {
+		b, err := os.ReadFile(config.KeycloakClientSecretFile)
+		if err != nil {
+			return errors.New("unable to read keycloak client secret file: " + err.Error())
+		}
+		config.KeycloakClientSecret = strings.TrimSpace(string(b))
+	}
+	return config.Validate()
+}
+
+// Applies all connection settings of the config
+func WithConfig(config Config) Option {
+	return func(o *options) {
+		o.address = config.VaultUrl
+		o.role = config.VaultRole
+		o.engine = config.VaultEngine
+		o.authUrl = config.KeycloakUrl
+		o.authRealm = config.KeycloakRealm
+		o.authClientId = config.KeycloakClientId
+		o.authClientSecret = config.KeycloakClientSecret
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+type CopyOptions struct {
+	History        bool   // replays all versions that are not destroyed instead of the current version only, deleted versions stay deleted
+	CustomMetadata bool   // copies the custom metadata
+	Overwrite      bool   // writes into existing secrets, otherwise ErrAlreadyExists is returned
+	Target         *Vault // engine to copy to, e.g. created with Engine. Defaults to the same engine.
+}
+
+// Copies the secret with the specified key to dst
+func (vault *Vault) Copy(src string, dst string, opts CopyOptions) error {
+	return vault.CopyCtx(context.Background(), src, dst, opts)
+}
+
+// Same as Copy, but the requests are bound to the provided context
+func (vault *Vault) CopyCtx(ctx context.Context, src string, dst string, opts CopyOptions) error {
+	target := opts.Target
+	if target == nil {
+		target = vault
+	}
+	if target.vaultEngine == vault.vaultEngine && src == dst {
+		return errors.New("source and destination are the same")
+	}
+	if opts.History || opts.CustomMetadata {
+		if err := vault.requireVersioning(); err != nil {
+			return err
+		}
+	}
+	if opts.CustomMetadata {
+		if err := target.requireVersioning(); err != nil {
+			return err
+		}
+	}
+	if !opts.Overwrite {
+		exists, err := target.exists(ctx, dst)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrAlreadyExists
+		}
+	}
+	if opts.History && target.kvVersion != 1 {
+		err := vault.replayHistory(ctx, src, target, dst)
+		if err != nil {
+			return err
+		}
+	} else {
+		data, _, err := vault.readSecret(ctx, src, 0)
+		if err != nil {
+			return err
+		}
+		err = target.WriteCtx(ctx, dst, data)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.CustomMetadata {
+		meta, err := vault.GetSecretMetadataCtx(ctx, src)
+		if err != nil {
+			return err
+		}
+		err = target.PatchMetadataCtx(ctx, dst, MetadataSettings{CustomMetadata: meta.CustomMetadata})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copies the secret with the specified key to dst like Copy and purges the source afterwards
+func (vault *Vault) Move(src string, dst string, opts CopyOptions) error {
+	return vault.MoveCtx(context.Background(), src, dst, opts)
+}
+
+// Same as Move, but the requests are bound to the provided context
+func (vault *Vault) MoveCtx(ctx context.Context, src string, dst string, opts CopyOptions) error {
+	err := vault.CopyCtx(ctx, src, dst, opts)
+	if err != nil {
+		return err
+	}
+	return vault.PurgeCtx(ctx, src)
+}
+
+// Copies all secrets below srcPrefix to the same relative path below dstPrefix like Copy. Stops at the first error.
+// Without History, secrets whose current version is deleted are skipped.
+func (vault *Vault) CopyTree(srcPrefix string, dstPrefix string, opts CopyOptions) error {
+	return vault.CopyTreeCtx(context.Background(), srcPrefix, dstPrefix, opts)
+}
+
+// Same as CopyTree, but the requests are bound to the provided context
+func (vault *Vault) CopyTreeCtx(ctx context.Context, srcPrefix string, dstPrefix string, opts CopyOptions) error {
+	return vault.forTree(ctx, srcPrefix, dstPrefix, func(src string, dst string) error {
+		return vault.CopyCtx(ctx, src, dst, opts)
+	})
+}
+
+// Moves all secrets below srcPrefix to the same relative path below dstPrefix like Move. Stops at the first error.
+// Without History, secrets whose current version is deleted are skipped and not purged.
+func (vault *Vault) MoveTree(srcPrefix string, dstPrefix string, opts CopyOptions) error {
+	return vault.MoveTreeCtx(context.Background(), srcPrefix, dstPrefix, opts)
+}
+
+// Same as MoveTree, but the requests are bound to the provided context
+func (vault *Vault) MoveTreeCtx(ctx context.Context, srcPrefix string, dstPrefix string, opts CopyOptions) error {
+	return vault.forTree(ctx, srcPrefix, dstPrefix, func(src string, dst string) error {
+		return vault.MoveCtx(ctx, src, dst, opts)
+	})
+}
+
+func (vault *Vault) forTree(ctx context.Context, srcPrefix string, dstPrefix string, fn func(src string, dst string) error) error {
+	srcPrefix = normalizePrefix(srcPrefix)
+	dstPrefix = normalizePrefix(dstPrefix)
+	keys, err := vault.ListRecursiveCtx(ctx, srcPrefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = fn(key, dstPrefix+strings.TrimPrefix(key, srcPrefix))
+		if errors.Is(err, ErrNotFound) { // current version deleted or purged since listing, the source is left unchanged
+			continue
+		}
+		if err != nil {
+			return errors.Join(errors.New("unable to process "+key), err)
+		}
+	}
+	return nil
+}
+
+// Writes all versions of the source that are not destroyed to the target, deleted versions are deleted after writing
+func (vault *Vault) replayHistory(ctx context.Context, src string, target *Vault, dst string) error {
+	history, err := vault.HistoryCtx(ctx, src)
+	if err != nil {
+		return err
+	}
+	for _, version := range history {
+		if version.Metadata.Destroyed {
+			continue
+		}
+		data := version.Data
+		if data == nil {
+			data, err = vault.readDeletedVersion(ctx, src, version.Metadata.Version)
+			if err != nil {
+				return err
+			}
+		}
+		written, err := target.writeSecret(ctx, dst, data, nil)
+		if err != nil {
+			return err
+		}
+		if version.Metadata.IsDeleted() && written != nil {
+			err = target.deleteVersions(ctx, dst, []int{written.Version})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns true if the key exists. For KV version 2 engines deleted or destroyed secrets exist until purged.
+func (vault *Vault) exists(ctx context.Context, key string) (bool, error) {
+	var err error
+	if vault.kvVersion == 1 {
+		_, _, err = vault.readSecret(ctx, key, 0)
+	} else {
+		_, err = vault.GetSecretMetadataCtx(ctx, key)
+	}
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault/vaultjwt"
+	vaultApi "github.com/hashicorp/vault/api"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrPermissionDenied = vaultjwt.ErrPermissionDenied
+	ErrSealed           = vaultjwt.ErrSealed
+	ErrCASConflict      = errors.New("check-and-set conflict")
+	ErrVersionDeleted   = errors.New("version is deleted")
+	ErrVersionDestroyed = errors.New("version is destroyed")
+	ErrAlreadyExists    = errors.New("already exists")
+)
+
+// Returned if keycloak or the vault JWT login rejects the login. Matches ErrPermissionDenied with errors.Is if access was denied
+// and ErrSealed if vault is sealed.
+type AuthError = vaultjwt.AuthError
+
+// Returned if vault responds with an unexpected status code. Matches ErrNotFound, ErrPermissionDenied, ErrSealed and ErrCASConflict with errors.Is.
+type StatusError struct {
+	Code int
+	Body string
+	Path string
+}
+
+func (e *StatusError) Error() string {
+	msg := "unexpected status code " + strconv.Itoa(e.Code) + " for " + e.Path
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == http.StatusNotFound
+	case ErrPermissionDenied:
+		return e.Code == http.StatusForbidden
+	case ErrSealed:
+		return e.Code == http.StatusServiceUnavailable
+	case ErrCASConflict:
+		return e.Code == http.StatusBadRequest && strings.Contains(e.Body, "check-and-set parameter did not match")
+	default:
+		return false
+	}
+}
+
+// Converts errors of the vault api into a *StatusError, other errors are returned unchanged
+func wrapError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	var respErr *vaultApi.ResponseError
+	if errors.As(err, &respErr) {
+		return &StatusError{Code: respErr.StatusCode, Body: strings.Join(respErr.Errors, ", "), Path: path}
+	}
+	return err
+}
+
+// Checks the response of a raw request
+func checkResponse(resp *vaultApi.Response, err error, path string) error {
+	if err != nil {
+		return wrapError(err, path)
+	}
+	if resp == nil {
+		return errors.New("empty response for " + path)
+	}
+	if resp.StatusCode > 299 {
+		return &StatusError{Code: resp.StatusCode, Path: path}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type FallbackOptions struct {
+	Dir    string        // directory of the persisted secrets, created if missing
+	Key    []byte        // AES key with 16, 24 or 32 bytes used to encrypt the persisted secrets
+	MaxAge time.Duration // persisted secrets older than MaxAge are not used, 0 means unlimited
+}
+
+// Persists every secret read with Read to disk. If vault is unreachable or sealed, Read returns the persisted value instead.
+// Use ReadWithInfo to check if a value was read from the fallback. With a fallback New succeeds even if vault is unreachable
+// and the login is retried in the background, set the KV version with WithKVVersion in this case.
+func WithFallback(fallbackOptions FallbackOptions) Option {
+	return func(o *options) {
+		o.fallback = &fallbackOptions
+	}
+}
+
+type ReadResult struct {
+	Data         map[string]interface{}
+	FromFallback bool      // true if vault was unreachable and Data was read from the fallback
+	StoredAt     time.Time // time Data was persisted to the fallback, only set if FromFallback is true
+}
+
+// Reads the secret with the specified key like Read, but also reports if the value was read from the fallback
+func (vault *Vault) ReadWithInfo(key string) (*ReadResult, error) {
+	return vault.ReadWithInfoCtx(context.Background(), key)
+}
+
+// Same as ReadWithInfo, but the request is bound to the provided context
+func (vault *Vault) ReadWithInfoCtx(ctx context.Context, key string) (*ReadResult, error) {
+	path := vault.path("data", key)
+	var generation uint64
+	if vault.cache != nil {
+		data, err, gen, ok := vault.cache.get(path)
+		if ok {
+			return &ReadResult{Data: data}, err
+		}
+		generation = gen
+	}
+	data, _, err := vault.readSecret(ctx, key, 0)
+	if vault.cache != nil && (err == nil || errors.Is(err, ErrNotFound)) {
+		vault.cache.put(path, key, data, err, generation)
+	}
+	if vault.fallback == nil {
+		return &ReadResult{Data: data}, err
+	}
+	switch {
+	case err == nil:
+		vault.fallback.store(path, data)
+	case errors.Is(err, ErrNotFound):
+		vault.fallback.remove(path)
+	case isUnavailable(err):
+		stored, storedAt, ok := vault.fallback.load(path)
+		if ok {
+			vault.logger.Println("WARN: [VAULT] Vault unavailable, using fallback value of " + key + " stored at " + storedAt.Format(time.RFC3339) + ": " + err.Error())
+			return &ReadResult{Data: stored, FromFallback: true, StoredAt: storedAt}, nil
+		}
+	}
+	return &ReadResult{Data: data}, err
+}
+
+// Removes the secret from the read cache and the fallback
+func (vault *Vault) invalidate(key string) {
+	vault.InvalidateCache(key)
+	if vault.fallback != nil {
+		vault.fallback.remove(vault.path("data", key))
+	}
+}
+
+// Returns true if the error indicates that vault or keycloak could not be reached or are unavailable
+func isUnavailable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrSealed) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusBadGateway || statusErr.Code == http.StatusGatewayTimeout
+	}
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return authErr.StatusCode == http.StatusBadGateway || authErr.StatusCode == http.StatusServiceUnavailable || authErr.StatusCode == http.StatusGatewayTimeout
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+type fallbackStore struct {
+	options   FallbackOptions
+	logger    *log.Logger
+	mux       sync.Mutex                // guards the files and persisted
+	persisted map[string]persistedEntry // entries written by this store, used to skip rewriting unchanged values
+}
+
+type persistedEntry struct {
+	hash     [sha256.Size]byte
+	storedAt time.Time
+}
+
+type fallbackEntry struct {
+	Path     string                 `json:"path"`
+	StoredAt time.Time              `json:"stored_at"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+func newFallbackStore(options FallbackOptions, logger *log.Logger) (*fallbackStore, error) {
+	err := os.MkdirAll(options.Dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &fallbackStore{options: options, logger: logger, persisted: map[string]persistedEntry{}}, nil
+}
+
+// File names are hashed, so the persisted files do not reveal the secret paths
+func (store *fallbackStore) file(path string) string {
+	hash := sha256.Sum256([]byte(path))
+	return filepath.Join(store.options.Dir, hex.EncodeToString(hash[:]))
+}
+
+// Persists the data, unless the same data was persisted recently. Unchanged data is rewritten after half of MaxAge,
+// so the stored time does not exceed MaxAge while vault is available.
+func (store *fallbackStore) store(path string, data map[string]interface{}) {
+	err := store.write(path, data)
+	if err != nil {
+		store.logger.Println("WARN: [VAULT] Unable to persist fallback value: " + err.Error())
+	}
+}
+
+// Writes the encrypted entry to a temporary file and renames it, so readers never see partially written files
+func (store *fallbackStore) write(path string, data map[string]interface{}) error {
+	dataBytes, err := json.Marshal(data) // map keys are sorted, so equal data results in equal hashes
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(dataBytes)
+	now := time.Now()
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	if previous, ok := store.persisted[path]; ok && previous.hash == hash && (store.options.MaxAge <= 0 || now.Sub(previous.storedAt) < store.options.MaxAge/2) {
+		return nil
+	}
+	plaintext, err := json.Marshal(fallbackEntry{Path: path, StoredAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+	ciphertext, err := encrypt(store.options.Key, plaintext)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(store.options.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(ciphertext)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), store.file(path))
+	if err != nil {
+		return err
+	}
+	store.persisted[path] = persistedEntry{hash: hash, storedAt: now}
+	return nil
+}
+
+// Returns the persisted data, unless it is missing, unreadable or older than MaxAge
+func (store *fallbackStore) load(path string) (data map[string]interface{}, storedAt time.Time, ok bool) {
+	store.mux.Lock()
+	ciphertext, err := os.ReadFile(store.file(path))
+	store.mux.Unlock()
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			store.logger.Println("WARN: [VAULT] Unable to read fallback value: " + err.Error())
+		}
+		return nil, time.Time{}, false
+	}
+	plaintext, err := decrypt(store.options.Key, ciphertext)
+	if err != nil {
+		store.logger.Println("WARN: [VAULT] Unable to read fallback value: " + err.Error())
+		return nil, time.Time{}, false
+	}
+	entry := fallbackEntry{}
+	decoder := json.NewDecoder(bytes.NewReader(plaintext))
+	decoder.UseNumber() // numbers are returned as json.Number like by vault reads
+	err = decoder.Decode(&entry)
+	if err != nil || entry.Path != path {
+		store.logger.Println("WARN: [VAULT] Ignoring invalid fallback value for " + path)
+		return nil, time.Time{}, false
+	}
+	if store.options.MaxAge > 0 && time.Since(entry.StoredAt) > store.options.MaxAge {
+		store.logger.Println("WARN: [VAULT] Ignoring fallback value for " + path + ", stored at " + entry.StoredAt.Format(time.RFC3339) + " exceeds max age")
+		return nil, time.Time{}, false
+	}
+	return entry.Data, entry.StoredAt, true
+}
+
+func (store *fallbackStore) remove(path string) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	delete(store.persisted, path)
+	err := os.Remove(store.file(path))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		store.logger.Println("WARN: [VAULT] Unable to remove fallback value: " + err.Error())
+	}
+}
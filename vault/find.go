@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Deletion state of the current version of a secret
+type DeletionState int
+
+const (
+	AnyDeletionState DeletionState = iota
+	NotDeleted                     // the current version is readable
+	Deleted                        // the current version is deleted, but can be undeleted
+	Destroyed                      // the current version is destroyed permanently
+)
+
+// Conditions for Find. Zero values are ignored, all other conditions must match.
+type Filter struct {
+	CustomMetadata map[string]string // custom metadata entries the secret must have
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	UpdatedAfter   time.Time
+	UpdatedBefore  time.Time // e.g. time.Now().AddDate(0, 0, -90) for secrets not rotated in 90 days
+	Deletion       DeletionState
+}
+
+type FindResult struct {
+	Path     string
+	Metadata *SecretMetadata
+}
+
+// Checks if the metadata fulfills all conditions of the filter
+func (filter Filter) Match(meta *SecretMetadata) bool {
+	for k, v := range filter.CustomMetadata {
+		actual, ok := meta.CustomMetadata[k]
+		if !ok || actual != v {
+			return false
+		}
+	}
+	if !filter.CreatedAfter.IsZero() && !meta.CreatedTime.After(filter.CreatedAfter) {
+		return false
+	}
+	if !filter.CreatedBefore.IsZero() && !meta.CreatedTime.Before(filter.CreatedBefore) {
+		return false
+	}
+	if !filter.UpdatedAfter.IsZero() && !meta.UpdatedTime.After(filter.UpdatedAfter) {
+		return false
+	}
+	if !filter.UpdatedBefore.IsZero() && !meta.UpdatedTime.Before(filter.UpdatedBefore) {
+		return false
+	}
+	if filter.Deletion != AnyDeletionState && filter.Deletion != meta.DeletionState() {
+		return false
+	}
+	return true
+}
+
+// Returns the deletion state of the current version
+func (meta *SecretMetadata) DeletionState() DeletionState {
+	current, ok := meta.Current()
+	switch {
+	case !ok:
+		return Destroyed // no longer retained
+	case current.Destroyed:
+		return Destroyed
+	case current.IsDeleted():
+		return Deleted
+	default:
+		return NotDeleted
+	}
+}
+
+// Searches all secrets below the prefix and returns the paths and metadata of all secrets matching the filter, sorted by path
+func (vault *Vault) Find(prefix string, filter Filter) ([]FindResult, error) {
+	return vault.FindCtx(context.Background(), prefix, filter)
+}
+
+// Same as Find, but the requests are bound to the provided context
+func (vault *Vault) FindCtx(ctx context.Context, prefix string, filter Filter) ([]FindResult, error) {
+	keys, err := vault.ListRecursiveCtx(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	metas := make([]*SecretMetadata, len(keys))
+	var (
+		mux      sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, vault.walkConcurrency)
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			meta, err := vault.GetSecretMetadataCtx(ctx, keys[i])
+			if errors.Is(err, ErrNotFound) { // purged since listing
+				return
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+			metas[i] = meta
+		}(i)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	results := []FindResult{}
+	for i, key := range keys {
+		if metas[i] != nil && filter.Match(metas[i]) {
+			results = append(results, FindResult{Path: key, Metadata: metas[i]})
+		}
+	}
+	return results, nil
+}
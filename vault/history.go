@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+)
+
+// A retained version of a secret
+type SecretVersion struct {
+	Metadata Metadata
+	Data     map[string]interface{} // nil if the version is deleted or destroyed
+}
+
+// Differences between two versions of a secret, compared by top level fields
+type SecretDiff struct {
+	Added   map[string]interface{}
+	Removed map[string]interface{}
+	Changed map[string]FieldChange
+}
+
+type FieldChange struct {
+	Old interface{}
+	New interface{}
+}
+
+// Returns true if both versions contain the same data
+func (diff SecretDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// Reads all retained versions of the secret with the specified key, ordered from oldest to newest.
+// Deleted and destroyed versions are included without data.
+func (vault *Vault) History(key string) ([]SecretVersion, error) {
+	return vault.HistoryCtx(context.Background(), key)
+}
+
+// Same as History, but the requests are bound to the provided context
+func (vault *Vault) HistoryCtx(ctx context.Context, key string) ([]SecretVersion, error) {
+	meta, err := vault.GetSecretMetadataCtx(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(meta.Versions))
+	for version := range meta.Versions {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	result := make([]SecretVersion, 0, len(versions))
+	for _, version := range versions {
+		entry := SecretVersion{Metadata: meta.Versions[version]}
+		if !entry.Metadata.Destroyed && !entry.Metadata.IsDeleted() {
+			entry.Data, err = vault.ReadVersionCtx(ctx, key, version)
+			if err != nil && !errors.Is(err, ErrNotFound) { // deleted in the meantime or by delete_version_after
+				return nil, err
+			}
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// Compares two versions of the secret with the specified key. Version 0 refers to the current version.
+// Returns ErrNotFound if one of the versions is deleted or destroyed.
+func (vault *Vault) Diff(key string, v1 int, v2 int) (SecretDiff, error) {
+	return vault.DiffCtx(context.Background(), key, v1, v2)
+}
+
+// Same as Diff, but the requests are bound to the provided context
+func (vault *Vault) DiffCtx(ctx context.Context, key string, v1 int, v2 int) (SecretDiff, error) {
+	if err := vault.requireVersioning(); err != nil {
+		return SecretDiff{}, err
+	}
+	old, _, err := vault.readSecret(ctx, key, v1)
+	if err != nil {
+		return SecretDiff{}, err
+	}
+	updated, _, err := vault.readSecret(ctx, key, v2)
+	if err != nil {
+		return SecretDiff{}, err
+	}
+	return DiffData(old, updated), nil
+}
+
+// Compares the top level fields of two secrets
+func DiffData(old map[string]interface{}, updated map[string]interface{}) SecretDiff {
+	diff := SecretDiff{
+		Added:   map[string]interface{}{},
+		Removed: map[string]interface{}{},
+		Changed: map[string]FieldChange{},
+	}
+	for k, oldValue := range old {
+		newValue, ok := updated[k]
+		if !ok {
+			diff.Removed[k] = oldValue
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			diff.Changed[k] = FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	for k, newValue := range updated {
+		if _, ok := old[k]; !ok {
+			diff.Added[k] = newValue
+		}
+	}
+	return diff
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Returned by operations that require a KV version 2 engine. Also matches errors.ErrUnsupported.
+var ErrUnsupported = fmt.Errorf("%w by KV version 1 engine", errors.ErrUnsupported)
+
+// Sets the version (1 or 2) of the KV engine. If not set, the version is detected from the mount configuration.
+func WithKVVersion(version int) Option {
+	return func(o *options) {
+		o.kvVersion = version
+	}
+}
+
+// Returns the version (1 or 2) of the KV engine
+func (vault *Vault) KVVersion() int {
+	return vault.kvVersion
+}
+
+// Returns a handle for another KV engine that shares the login, HTTP client and background token renewal of this vault.
+// The KV version of the engine is detected from its mount configuration. The login ends when the vault created with New is closed.
+func (vault *Vault) Engine(name string) *Vault {
+	return vault.EngineCtx(context.Background(), name)
+}
+
+// Same as Engine, but the KV version detection is bound to the provided context
+func (vault *Vault) EngineCtx(ctx context.Context, name string) *Vault {
+	handle := &Vault{
+		authSession:     vault.authSession,
+		vaultEngine:     name,
+		casRetries:      vault.casRetries,
+		walkConcurrency: vault.walkConcurrency,
+	}
+	handle.initKVVersion(ctx)
+	return handle
+}
+
+// Detects the version of the KV engine and falls back to version 2 if the detection fails
+func (vault *Vault) initKVVersion(ctx context.Context) {
+	var err error
+	vault.kvVersion, err = vault.detectKVVersion(ctx)
+	if err != nil {
+		vault.logger.Println("WARN: [VAULT] Unable to detect KV version of " + vault.vaultEngine + ", assuming version 2: " + err.Error())
+		vault.kvVersion = 2
+	}
+}
+
+// Reads the version of the KV engine from the mount configuration
+func (vault *Vault) detectKVVersion(ctx context.Context) (int, error) {
+	path := "sys/internal/ui/mounts/" + vault.vaultEngine
+	secret, err := vault.client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return 0, wrapError(err, path)
+	}
+	if secret == nil {
+		return 0, ErrNotFound
+	}
+	options, _ := secret.Data["options"].(map[string]interface{})
+	version, _ := options["version"].(string)
+	switch version {
+	case "", "1":
+		return 1, nil
+	case "2":
+		return 2, nil
+	default:
+		return 0, errors.New("unknown KV version " + version)
+	}
+}
+
+// Returns the api path of the key. For KV version 2 engines the kind (data, metadata, undelete, destroy) is inserted after the engine.
+func (vault *Vault) path(kind string, key string) string {
+	path := vault.vaultEngine
+	if vault.kvVersion != 1 {
+		path += "/" + kind
+	}
+	if key != "" {
+		path += "/" + key
+	}
+	return path
+}
+
+// Returns ErrUnsupported for KV version 1 engines
+func (vault *Vault) requireVersioning() error {
+	if vault.kvVersion == 1 {
+		return ErrUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const DefaultWalkConcurrency = 8
+
+// Returned by a WalkFunc to skip the contents of the directory passed to it
+var SkipDir = errors.New("skip this directory")
+
+// Called by Walk for every key and directory. Directories end with a slash.
+type WalkFunc func(path string, isDir bool) error
+
+// Lists the keys and directories directly below the prefix. Directories end with a slash, e.g. "app/".
+func (vault *Vault) ListKeysPrefix(prefix string) ([]string, error) {
+	return vault.ListKeysPrefixCtx(context.Background(), prefix)
+}
+
+// Same as ListKeysPrefix, but the request is bound to the provided context
+func (vault *Vault) ListKeysPrefixCtx(ctx context.Context, prefix string) ([]string, error) {
+	return vault.listPath(ctx, normalizePrefix(prefix))
+}
+
+// Lists the full paths of all keys below the prefix in all directories, sorted alphabetically
+func (vault *Vault) ListRecursive(prefix string) ([]string, error) {
+	return vault.ListRecursiveCtx(context.Background(), prefix)
+}
+
+// Same as ListRecursive, but the requests are bound to the provided context
+func (vault *Vault) ListRecursiveCtx(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	err := vault.WalkCtx(ctx, prefix, func(path string, isDir bool) error {
+		if !isDir {
+			keys = append(keys, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Calls fn for every key and directory below the prefix. Directories are listed concurrently (see WithWalkConcurrency),
+// but fn is never called concurrently. If fn returns SkipDir for a directory, its contents are skipped. Any other error
+// stops the walk and is returned.
+func (vault *Vault) Walk(prefix string, fn WalkFunc) error {
+	return vault.WalkCtx(context.Background(), prefix, fn)
+}
+
+// Same as Walk, but the requests are bound to the provided context
+func (vault *Vault) WalkCtx(ctx context.Context, prefix string, fn WalkFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mux      sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	sem := make(chan struct{}, vault.walkConcurrency)
+	var walk func(dir string)
+	walk = func(dir string) {
+		defer wg.Done()
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		keys, err := vault.listPath(ctx, dir)
+		<-sem
+		mux.Lock()
+		defer mux.Unlock()
+		if err != nil {
+			fail(err)
+			return
+		}
+		for _, key := range keys {
+			if firstErr != nil {
+				return
+			}
+			path := dir + key
+			isDir := strings.HasSuffix(key, "/")
+			err = fn(path, isDir)
+			if isDir && err == SkipDir {
+				continue
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+			if isDir {
+				wg.Add(1)
+				go walk(path)
+			}
+		}
+	}
+	wg.Add(1)
+	go walk(normalizePrefix(prefix))
+	wg.Wait()
+	if firstErr == nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// Sets how many directories Walk lists concurrently. Defaults to DefaultWalkConcurrency.
+func WithWalkConcurrency(concurrency int) Option {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return func(o *options) {
+		o.walkConcurrency = concurrency
+	}
+}
+
+func (vault *Vault) listPath(ctx context.Context, dir string) ([]string, error) {
+	path := vault.path("metadata", dir)
+	secret, err := vault.client.Logical().ListWithContext(ctx, path)
+	if err != nil {
+		return nil, wrapError(err, path)
+	}
+	if secret == nil {
+		return []string{}, nil
+	}
+	keys, ok := secret.Data["keys"]
+	if !ok {
+		return []string{}, nil
+	}
+
+	keySlice, ok := keys.([]interface{})
+	if !ok {
+		return nil, errors.New("unexpected type of keys")
+	}
+	keysStringSlice := []string{}
+	for _, key := range keySlice {
+		keyString, ok := key.(string)
+		if !ok {
+			return nil, errors.New("unexpected key type")
+		}
+		keysStringSlice = append(keysStringSlice, keyString)
+	}
+	return keysStringSlice, nil
+}
+
+// Removes leading slashes and ensures a trailing slash for non-empty prefixes
+func normalizePrefix(prefix string) string {
+	prefix = strings.TrimLeft(prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
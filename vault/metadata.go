@@ -0,0 +1,112 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Provides the metadata of the secret with the specified key including all retained versions. Returns ErrNotFound if the secret is not present.
+func (vault *Vault) GetSecretMetadata(key string) (*SecretMetadata, error) {
+	return vault.GetSecretMetadataCtx(context.Background(), key)
+}
+
+// Same as GetSecretMetadata, but the request is bound to the provided context
+func (vault *Vault) GetSecretMetadataCtx(ctx context.Context, key string) (*SecretMetadata, error) {
+	if err := vault.requireVersioning(); err != nil {
+		return nil, err
+	}
+	path := vault.path("metadata", key)
+	secret, err := vault.client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return nil, wrapError(err, path)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrNotFound
+	}
+	return getSecretMetadata(secret.Data)
+}
+
+// Updates the settings of the secret with the specified key. Custom metadata is replaced if set.
+// Settings can be updated before the secret is written for the first time.
+func (vault *Vault) UpdateMetadata(key string, settings MetadataSettings) error {
+	return vault.UpdateMetadataCtx(context.Background(), key, settings)
+}
+
+// Same as UpdateMetadata, but the request is bound to the provided context
+func (vault *Vault) UpdateMetadataCtx(ctx context.Context, key string, settings MetadataSettings) error {
+	if err := vault.requireVersioning(); err != nil {
+		return err
+	}
+	path := vault.path("metadata", key)
+	_, err := vault.client.Logical().WriteWithContext(ctx, path, settings.body())
+	return wrapError(err, path)
+}
+
+// Updates the settings of the secret with the specified key. Custom metadata is merged into the existing custom metadata,
+// the custom metadata keys listed in remove are deleted. Returns ErrNotFound if the secret is not present.
+func (vault *Vault) PatchMetadata(key string, settings MetadataSettings, remove ...string) error {
+	return vault.PatchMetadataCtx(context.Background(), key, settings, remove...)
+}
+
+// Same as PatchMetadata, but the request is bound to the provided context
+func (vault *Vault) PatchMetadataCtx(ctx context.Context, key string, settings MetadataSettings, remove ...string) error {
+	if err := vault.requireVersioning(); err != nil {
+		return err
+	}
+	path := vault.path("metadata", key)
+	body := settings.body()
+	if len(remove) > 0 {
+		custom := map[string]interface{}{}
+		for k, v := range settings.CustomMetadata {
+			custom[k] = v
+		}
+		for _, k := range remove {
+			custom[k] = nil
+		}
+		body["custom_metadata"] = custom
+	}
+	_, err := vault.client.Logical().JSONMergePatch(ctx, path, body)
+	err = wrapError(err, path)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusMethodNotAllowed {
+		return vault.patchMetadataByReadAndWrite(ctx, key, settings, remove)
+	}
+	return err
+}
+
+// Fallback for vault versions without PATCH support. Not atomic, concurrent changes to the custom metadata may be lost.
+func (vault *Vault) patchMetadataByReadAndWrite(ctx context.Context, key string, settings MetadataSettings, remove []string) error {
+	meta, err := vault.GetSecretMetadataCtx(ctx, key)
+	if err != nil {
+		return err
+	}
+	custom := map[string]string{}
+	for k, v := range meta.CustomMetadata {
+		custom[k] = v
+	}
+	for k, v := range settings.CustomMetadata {
+		custom[k] = v
+	}
+	for _, k := range remove {
+		delete(custom, k)
+	}
+	settings.CustomMetadata = custom
+	return vault.UpdateMetadataCtx(ctx, key, settings)
+}
@@ -26,9 +26,34 @@ type Metadata struct {
 	CreatedTime    NanoTime               `json:"created_time"`
 	CustomMetadata map[string]interface{} `json:"custom_metadata,omitempty"`
 	DeletionTime   *NanoTime              `json:"deletion_time,omitempty"`
+	Destroyed      bool                   `json:"destroyed,omitempty"`
 	Version        int                    `json:"version,omitempty"`
 }
 
+// Returns true if the version is deleted. With delete_version_after versions have a future deletion time until they expire.
+func (meta Metadata) IsDeleted() bool {
+	return meta.DeletionTime != nil && !meta.DeletionTime.After(time.Now())
+}
+
+// Metadata of a secret and all its retained versions
+type SecretMetadata struct {
+	CasRequired        bool              `json:"cas_required"`
+	CreatedTime        NanoTime          `json:"created_time"`
+	CurrentVersion     int               `json:"current_version"`
+	CustomMetadata     map[string]string `json:"custom_metadata,omitempty"`
+	DeleteVersionAfter Duration          `json:"delete_version_after"`
+	MaxVersions        int               `json:"max_versions"`
+	OldestVersion      int               `json:"oldest_version"`
+	UpdatedTime        NanoTime          `json:"updated_time"`
+	Versions           map[int]Metadata  `json:"versions"`
+}
+
+// Returns the metadata of the current version
+func (meta *SecretMetadata) Current() (Metadata, bool) {
+	m, ok := meta.Versions[meta.CurrentVersion]
+	return m, ok
+}
+
 type NanoTime struct {
 	time.Time
 }
@@ -43,6 +68,49 @@ func (nt *NanoTime) UnmarshalJSON(b []byte) (err error) {
 	return
 }
 
+// Settings of a secret for UpdateMetadata and PatchMetadata. Nil fields are left unchanged.
+type MetadataSettings struct {
+	MaxVersions        *int              // number of retained versions, 0 uses the engine setting
+	CasRequired        *bool             // requires check-and-set for all writes
+	DeleteVersionAfter *time.Duration    // deletes versions after the duration, 0 disables deletion
+	CustomMetadata     map[string]string // e.g. owner, service or rotation info
+}
+
+func (settings MetadataSettings) body() map[string]interface{} {
+	body := map[string]interface{}{}
+	if settings.MaxVersions != nil {
+		body["max_versions"] = *settings.MaxVersions
+	}
+	if settings.CasRequired != nil {
+		body["cas_required"] = *settings.CasRequired
+	}
+	if settings.DeleteVersionAfter != nil {
+		body["delete_version_after"] = settings.DeleteVersionAfter.String()
+	}
+	if settings.CustomMetadata != nil {
+		body["custom_metadata"] = settings.CustomMetadata
+	}
+	return body
+}
+
+// Duration encoded as string like 1h30m0s
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) (err error) {
+	s := strings.Trim(string(b), "\"")
+	if s == "null" || len(s) == 0 {
+		return
+	}
+	d.Duration, err = time.ParseDuration(s)
+	return
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
 func getMetadata(r map[string]interface{}) (*Metadata, error) {
 	b, err := json.Marshal(r)
 	if err != nil {
@@ -61,6 +129,26 @@ func getMetadata(r map[string]interface{}) (*Metadata, error) {
 	return &meta, err
 }
 
+func getSecretMetadata(r map[string]interface{}) (*SecretMetadata, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var meta SecretMetadata
+	err = json.Unmarshal(b, &meta)
+	if err != nil {
+		return nil, err
+	}
+	for version, m := range meta.Versions {
+		m.Version = version
+		if m.DeletionTime != nil && m.DeletionTime.IsZero() { // json unmarshalls empty string to Zero
+			m.DeletionTime = nil
+		}
+		meta.Versions[version] = m
+	}
+	return &meta, nil
+}
+
 type DestroyVersionsBody struct {
 	Versions []string `json:"versions"`
 }
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Configures a Vault created with New
+type Option func(*options)
+
+type options struct {
+	address          string
+	role             string
+	authUrl          string
+	authRealm        string
+	authClientId     string
+	authClientSecret string
+	engine           string
+	httpClient       *http.Client
+	tlsConfig        *tls.Config
+	logger           *log.Logger
+	backoff          BackoffPolicy
+	casRetries       int
+	walkConcurrency  int
+	kvVersion        int
+	cache            *CacheOptions
+	fallback         *FallbackOptions
+}
+
+func defaultOptions() options {
+	return options{
+		logger:          log.Default(),
+		backoff:         DefaultBackoffPolicy,
+		casRetries:      DefaultCASRetries,
+		walkConcurrency: DefaultWalkConcurrency,
+	}
+}
+
+func (o *options) validate() error {
+	missing := []string{}
+	if o.address == "" {
+		missing = append(missing, "address")
+	}
+	if o.role == "" {
+		missing = append(missing, "role")
+	}
+	if o.authUrl == "" {
+		missing = append(missing, "keycloak url")
+	}
+	if o.authRealm == "" {
+		missing = append(missing, "keycloak realm")
+	}
+	if o.authClientId == "" {
+		missing = append(missing, "keycloak client id")
+	}
+	if o.engine == "" {
+		missing = append(missing, "engine")
+	}
+	if len(missing) > 0 {
+		return errors.New("missing vault options: " + strings.Join(missing, ", "))
+	}
+	if o.kvVersion < 0 || o.kvVersion > 2 {
+		return errors.New("invalid KV version " + strconv.Itoa(o.kvVersion))
+	}
+	if o.fallback != nil {
+		if o.fallback.Dir == "" {
+			return errors.New("missing fallback directory")
+		}
+		if keyLen := len(o.fallback.Key); keyLen != 16 && keyLen != 24 && keyLen != 32 {
+			return errors.New("invalid fallback key length " + strconv.Itoa(keyLen) + ", expected 16, 24 or 32 bytes")
+		}
+	}
+	return nil
+}
+
+// Sets the address of the vault, e.g. http://localhost:8200
+func WithAddress(address string) Option {
+	return func(o *options) {
+		o.address = address
+	}
+}
+
+// Sets the vault role used for the JWT login
+func WithRole(role string) Option {
+	return func(o *options) {
+		o.role = role
+	}
+}
+
+// Sets the keycloak instance and client used to obtain the JWT for the vault login
+func WithKeycloak(url, realm, clientId, clientSecret string) Option {
+	return func(o *options) {
+		o.authUrl = url
+		o.authRealm = realm
+		o.authClientId = clientId
+		o.authClientSecret = clientSecret
+	}
+}
+
+// Sets the mount path of the KV engine, e.g. secret
+func WithEngine(engine string) Option {
+	return func(o *options) {
+		o.engine = engine
+	}
+}
+
+// Sets the HTTP client used for requests to vault and keycloak
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// Sets the TLS configuration used for requests to vault and keycloak. Requires the transport of the HTTP client to be an *http.Transport.
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// Sets the logger used by the background token renewal. Defaults to the standard logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Sets the policy used to retry a failed re-authentication. Defaults to DefaultBackoffPolicy.
+func WithBackoff(policy BackoffPolicy) Option {
+	return func(o *options) {
+		o.backoff = policy
+	}
+}
+
+// Returns a copy of the client with the TLS configuration applied to its transport
+func withTLS(client *http.Client, config *tls.Config) (*http.Client, error) {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, errors.New("unable to apply TLS config to transport of type other than *http.Transport")
+	}
+	transport.TLSClientConfig = config.Clone()
+	c := *client
+	c.Transport = transport
+	return &c, nil
+}
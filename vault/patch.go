@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Updates the provided fields of the secret with the specified key and keeps all other fields (JSON merge patch).
+// Fields with a nil value are removed. Returns ErrNotFound if the secret is not present.
+// On vault versions without PATCH support the secret is read, merged and written with check-and-set.
+func (vault *Vault) Patch(key string, partial map[string]interface{}) error {
+	return vault.PatchCtx(context.Background(), key, partial)
+}
+
+// Same as Patch, but the request is bound to the provided context
+func (vault *Vault) PatchCtx(ctx context.Context, key string, partial map[string]interface{}) error {
+	defer vault.invalidate(key)
+	if err := vault.requireVersioning(); err != nil {
+		return err
+	}
+	path := vault.path("data", key)
+	_, err := vault.client.Logical().JSONMergePatch(ctx, path, map[string]interface{}{"data": partial})
+	err = wrapError(err, path)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusMethodNotAllowed {
+		return vault.patchByReadAndWrite(ctx, key, partial)
+	}
+	return err
+}
+
+// Marshals the interface and patches the secret with the specified key like Patch.
+// Use omitempty to leave fields of the secret unchanged.
+func (vault *Vault) PatchInterface(key string, partial interface{}) error {
+	return vault.PatchInterfaceCtx(context.Background(), key, partial)
+}
+
+// Same as PatchInterface, but the request is bound to the provided context
+func (vault *Vault) PatchInterfaceCtx(ctx context.Context, key string, partial interface{}) error {
+	b, err := json.Marshal(partial)
+	if err != nil {
+		return err
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return err
+	}
+	return vault.PatchCtx(ctx, key, m)
+}
+
+func (vault *Vault) patchByReadAndWrite(ctx context.Context, key string, partial map[string]interface{}) error {
+	return vault.UpdateCtx(ctx, key, func(old map[string]interface{}) (map[string]interface{}, error) {
+		if old == nil {
+			return nil, ErrNotFound
+		}
+		return mergePatch(old, partial), nil
+	})
+}
+
+// Applies the patch to the target as described in RFC 7386
+func mergePatch(target map[string]interface{}, patch map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(target))
+	for k, v := range target {
+		result[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		patchMap, ok := v.(map[string]interface{})
+		if !ok {
+			result[k] = v
+			continue
+		}
+		targetMap, ok := result[k].(map[string]interface{})
+		if !ok {
+			targetMap = map[string]interface{}{}
+		}
+		result[k] = mergePatch(targetMap, patchMap)
+	}
+	return result
+}
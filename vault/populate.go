@@ -0,0 +1,223 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Struct tag read by Populate
+const PopulateTag = "vault"
+
+// Reference to a secret parsed from a struct tag
+type secretRef struct {
+	key          string
+	version      int
+	field        string // empty for the whole secret
+	required     bool
+	defaultValue *string
+}
+
+type secretVersion struct {
+	key     string
+	version int
+}
+
+// Sets all fields of the struct target points to that are tagged with a secret reference:
+//
+//	Password string   `vault:"db/creds#password"`            // field password of the secret db/creds
+//	Old      string   `vault:"db/creds@3#password"`          // same, but from version 3
+//	Creds    DBCreds  `vault:"db/creds"`                     // the whole secret, decoded like Get
+//	User     string   `vault:"db/creds#user,required"`       // missing secrets are reported as error
+//	Port     int      `vault:"db/config#port,default=5432"`  // used if the secret or field is missing
+//
+// Values are decoded like Get, string values are also parsed as JSON for non-string fields. Untagged nested structs,
+// non-nil pointers to structs and slices of structs are populated recursively. Fields are left unchanged if their secret
+// is missing and neither required nor default are set. All errors, e.g. missing required secrets, are reported together.
+func Populate(ctx context.Context, v *Vault, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("populate target must be a non-nil pointer to a struct")
+	}
+	p := populator{vault: v, secrets: map[secretVersion]map[string]interface{}{}, errs: map[secretVersion]error{}}
+	p.populateStruct(ctx, value.Elem(), value.Elem().Type().Name())
+	return errors.Join(p.result...)
+}
+
+type populator struct {
+	vault   *Vault
+	secrets map[secretVersion]map[string]interface{} // every secret is read once
+	errs    map[secretVersion]error
+	result  []error
+}
+
+func (p *populator) populateStruct(ctx context.Context, value reflect.Value, path string) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldPath := path + "." + field.Name
+		tag, ok := field.Tag.Lookup(PopulateTag)
+		if !ok || tag == "-" {
+			if field.IsExported() {
+				p.populateNested(ctx, value.Field(i), fieldPath)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			p.result = append(p.result, errors.New("unable to populate unexported field "+fieldPath))
+			continue
+		}
+		ref, err := parseSecretRef(tag)
+		if err != nil {
+			p.result = append(p.result, errors.New("invalid vault tag of "+fieldPath+": "+err.Error()))
+			continue
+		}
+		err = p.populateField(ctx, value.Field(i), ref)
+		if err != nil {
+			p.result = append(p.result, errors.New("unable to populate "+fieldPath+" from "+tag+": "+err.Error()))
+		}
+	}
+}
+
+func (p *populator) populateNested(ctx context.Context, value reflect.Value, path string) {
+	switch value.Kind() {
+	case reflect.Struct:
+		p.populateStruct(ctx, value, path)
+	case reflect.Pointer:
+		if !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+			p.populateStruct(ctx, value.Elem(), path)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			p.populateNested(ctx, value.Index(i), path+"["+strconv.Itoa(i)+"]")
+		}
+	}
+}
+
+func (p *populator) populateField(ctx context.Context, field reflect.Value, ref secretRef) error {
+	data, err := p.read(ctx, ref.key, ref.version)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var value interface{} = data
+	if err == nil && ref.field != "" {
+		var ok bool
+		value, ok = data[ref.field]
+		if !ok {
+			err = errors.New("field " + ref.field + " " + ErrNotFound.Error())
+		}
+	}
+	switch {
+	case err == nil:
+		return assign(field, value)
+	case ref.defaultValue != nil:
+		return assign(field, *ref.defaultValue)
+	case ref.required:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (p *populator) read(ctx context.Context, key string, version int) (map[string]interface{}, error) {
+	id := secretVersion{key: key, version: version}
+	if data, ok := p.secrets[id]; ok {
+		return data, nil
+	}
+	if err, ok := p.errs[id]; ok {
+		return nil, err
+	}
+	var data map[string]interface{}
+	var err error
+	if version > 0 {
+		data, err = p.vault.ReadVersionCtx(ctx, key, version)
+	} else {
+		data, err = p.vault.ReadCtx(ctx, key)
+	}
+	if err != nil {
+		p.errs[id] = err
+		return nil, err
+	}
+	p.secrets[id] = data
+	return data, nil
+}
+
+// Parses tags like "key@version#field,required,default=value". The default value may contain commas.
+func parseSecretRef(tag string) (ref secretRef, err error) {
+	parts := strings.Split(tag, ",")
+	ref.key = parts[0]
+	if i := strings.Index(ref.key, "#"); i >= 0 {
+		ref.field = ref.key[i+1:]
+		ref.key = ref.key[:i]
+		if ref.field == "" {
+			return ref, errors.New("empty field")
+		}
+	}
+	if i := strings.LastIndex(ref.key, "@"); i >= 0 {
+		ref.version, err = strconv.Atoi(ref.key[i+1:])
+		if err != nil || ref.version <= 0 {
+			return ref, errors.New("invalid version " + ref.key[i+1:])
+		}
+		ref.key = ref.key[:i]
+	}
+	if ref.key == "" {
+		return ref, errors.New("empty key")
+	}
+	for i := 1; i < len(parts); i++ {
+		switch {
+		case parts[i] == "required":
+			ref.required = true
+		case strings.HasPrefix(parts[i], "default="):
+			defaultValue := strings.TrimPrefix(strings.Join(parts[i:], ","), "default=")
+			ref.defaultValue = &defaultValue
+			return ref, nil
+		default:
+			return ref, errors.New("unknown option " + parts[i])
+		}
+	}
+	return ref, nil
+}
+
+// Sets the field to the value. Strings are parsed as JSON for fields that are not strings or byte slices.
+// Numbers read from vault (json.Number) are set as text for string fields.
+func assign(field reflect.Value, value interface{}) error {
+	if n, ok := value.(json.Number); ok && field.Kind() == reflect.String {
+		field.SetString(n.String())
+		return nil
+	}
+	if s, ok := value.(string); ok {
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(s)
+			return nil
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+			field.SetBytes([]byte(s))
+			return nil
+		case json.Unmarshal([]byte(s), field.Addr().Interface()) == nil:
+			return nil
+		}
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, field.Addr().Interface())
+}
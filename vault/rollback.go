@@ -0,0 +1,117 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Custom metadata key in which Rollback records the version the secret was restored from
+const RollbackSourceMetadataKey = "rollback_source_version"
+
+// Writes the data of the specified version as new version of the secret and returns the new version number.
+// The write fails with ErrCASConflict if the secret is changed concurrently. Returns ErrVersionDeleted for deleted versions
+// unless force is set, in which case the version is undeleted temporarily to read it. Destroyed versions can not be
+// restored (ErrVersionDestroyed). The source version is recorded in the custom metadata (RollbackSourceMetadataKey).
+// If only recording the source version fails, the rollback was written and the new version is returned together with the error.
+func (vault *Vault) Rollback(key string, version int, force bool) (int, error) {
+	return vault.RollbackCtx(context.Background(), key, version, force)
+}
+
+// Same as Rollback, but the requests are bound to the provided context
+func (vault *Vault) RollbackCtx(ctx context.Context, key string, version int, force bool) (int, error) {
+	meta, err := vault.GetSecretMetadataCtx(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	target, ok := meta.Versions[version]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if target.Destroyed {
+		return 0, ErrVersionDestroyed
+	}
+	var data map[string]interface{}
+	if target.IsDeleted() {
+		if !force {
+			return 0, ErrVersionDeleted
+		}
+		data, err = vault.readDeletedVersion(ctx, key, version)
+	} else {
+		data, _, err = vault.readSecret(ctx, key, version)
+	}
+	if err != nil {
+		return 0, err
+	}
+	written, err := vault.writeSecret(ctx, key, data, &meta.CurrentVersion)
+	if err != nil {
+		return 0, err
+	}
+	newVersion := meta.CurrentVersion + 1
+	if written != nil {
+		newVersion = written.Version
+	}
+	err = vault.PatchMetadataCtx(ctx, key, MetadataSettings{CustomMetadata: map[string]string{RollbackSourceMetadataKey: strconv.Itoa(version)}})
+	if err != nil {
+		return newVersion, fmt.Errorf("rolled back to version %d, but unable to record source version: %w", newVersion, err)
+	}
+	return newVersion, nil
+}
+
+// Undeletes the version temporarily to read its data and deletes it again afterwards
+func (vault *Vault) readDeletedVersion(ctx context.Context, key string, version int) (map[string]interface{}, error) {
+	err := vault.UndeleteCtx(ctx, key, []int{version})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		deleteErr := vault.deleteVersions(context.Background(), key, []int{version})
+		if deleteErr != nil {
+			vault.logger.Println("ERROR: [VAULT] Unable to delete version " + strconv.Itoa(version) + " of " + key + " again: " + deleteErr.Error())
+		}
+	}()
+	data, _, err := vault.readSecret(ctx, key, version)
+	return data, err
+}
+
+// Soft deletes the specified versions of the secret
+func (vault *Vault) deleteVersions(ctx context.Context, key string, versions []int) error {
+	defer vault.invalidate(key)
+	if err := vault.requireVersioning(); err != nil {
+		return err
+	}
+	path := vault.path("delete", key)
+	r := vault.client.NewRequest(http.MethodPost, "/v1/"+path)
+	strVersions := make([]string, len(versions))
+	for i := range versions {
+		strVersions[i] = strconv.Itoa(versions[i])
+	}
+	bodyBytes, err := json.Marshal(DestroyVersionsBody{Versions: strVersions})
+	if err != nil {
+		return err
+	}
+	r.BodyBytes = bodyBytes
+	resp, err := vault.performRequest(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	return checkResponse(resp, err, path)
+}
@@ -17,60 +17,242 @@
 package vault
 
 import (
+	"context"
 	"errors"
+	"github.com/SENERGY-Platform/vault-jwt-go/vault/vaultjwt"
 	vaultApi "github.com/hashicorp/vault/api"
 	"log"
+	"strconv"
+	"sync"
+	"time"
 )
 
-func (vault *Vault) manageTokenLifecycle() {
+const tokenIncrement = 3600
+
+// Login and background token renewal, shared by a Vault and all handles created with Engine
+type authSession struct {
+	vaultJwt   *vaultjwt.VaultJwt
+	client     *vaultApi.Client
+	loginToken *vaultApi.Secret
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	mux        sync.RWMutex // guards loginToken, backoff, state and lastErr
+	loginMux   sync.Mutex   // serializes logins
+	tokenSwap  chan struct{}
+	backoff    BackoffPolicy
+	state      State
+	lastErr    error
+	logger     *log.Logger
+	cache      *readCache     // nil if caching is disabled
+	fallback   *fallbackStore // nil if the fallback is disabled
+}
+
+var errTokenSwapped = errors.New("login token was replaced")
+
+// Sets the policy used to retry a failed re-authentication
+func (session *authSession) SetBackoffPolicy(policy BackoffPolicy) {
+	session.mux.Lock()
+	defer session.mux.Unlock()
+	session.backoff = policy
+}
+
+// Returns the current authentication state of the vault
+func (session *authSession) State() State {
+	session.mux.RLock()
+	defer session.mux.RUnlock()
+	return session.state
+}
+
+// Returns the error of the last failed re-authentication or nil if the vault is authenticated
+func (session *authSession) LastError() error {
+	session.mux.RLock()
+	defer session.mux.RUnlock()
+	return session.lastErr
+}
+
+// Performs a new login and replaces the login token. The background renewal continues with the new token, also if it gave up before (StateFailed).
+func (session *authSession) Relogin(ctx context.Context) error {
+	err := session.loginCtx(ctx)
+	if err != nil {
+		return err
+	}
+	if session.ctx.Err() == nil {
+		session.setState(StateAuthenticated, nil)
+	}
+	select {
+	case session.tokenSwap <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Renews the login token immediately instead of waiting for the background renewal
+func (session *authSession) Renew(ctx context.Context) error {
+	secret, err := session.client.Auth().Token().RenewSelfWithContext(ctx, tokenIncrement)
+	if err != nil {
+		return err
+	}
+	session.setRenewedLoginToken(secret)
+	return nil
+}
+
+func (session *authSession) getLoginToken() *vaultApi.Secret {
+	session.mux.RLock()
+	defer session.mux.RUnlock()
+	return session.loginToken
+}
+
+func (session *authSession) setLoginToken(token *vaultApi.Secret) {
+	session.mux.Lock()
+	defer session.mux.Unlock()
+	session.loginToken = token
+}
+
+// Stores a renewed token, unless the login token was replaced by another login in the meantime
+func (session *authSession) setRenewedLoginToken(renewal *vaultApi.Secret) {
+	if renewal == nil || renewal.Auth == nil {
+		return
+	}
+	session.mux.Lock()
+	defer session.mux.Unlock()
+	if session.loginToken != nil && session.loginToken.Auth != nil && session.loginToken.Auth.ClientToken == renewal.Auth.ClientToken {
+		session.loginToken = renewal
+	}
+}
+
+func (session *authSession) setState(state State, err error) {
+	session.mux.Lock()
+	defer session.mux.Unlock()
+	session.state = state
+	session.lastErr = err
+}
+
+func (session *authSession) manageTokenLifecycle() {
+	defer close(session.done)
+	if session.getLoginToken() == nil && !session.reLoginOrWait() { // started without login, see WithFallback
+		return
+	}
 	for {
-		err := vault.runTokenWatcher() // new token watcher required after token changed
+		err := session.runTokenWatcher() // new token watcher required after token changed
+		if session.ctx.Err() != nil {
+			return
+		}
+		if err == errTokenSwapped {
+			continue
+		}
 		if err != nil {
-			log.Println("ERROR: [VAULT] " + err.Error())
+			session.logger.Println("ERROR: [VAULT] " + err.Error())
+		}
+		if !session.reLoginOrWait() {
+			return
+		}
+	}
+}
+
+// Same as reLogin, but if the backoff policy gives up, waits until Relogin succeeds. Returns false if the vault was closed.
+func (session *authSession) reLoginOrWait() bool {
+	if session.reLogin() {
+		return true
+	}
+	select {
+	case <-session.ctx.Done():
+		return false
+	case <-session.tokenSwap:
+		return true
+	}
+}
+
+// Attempts to login until it succeeds, the backoff policy gives up or the vault is closed. Returns true if logged in.
+func (session *authSession) reLogin() bool {
+	session.mux.RLock()
+	policy := session.backoff
+	session.mux.RUnlock()
+	for attempt := 1; ; attempt++ {
+		err := session.login()
+		if err == nil {
+			session.setState(StateAuthenticated, nil)
+			return true
+		}
+		if session.ctx.Err() != nil {
+			return false
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			session.logger.Println("ERROR: [VAULT] Giving up login after " + strconv.Itoa(attempt) + " attempts: " + err.Error())
+			session.setState(StateFailed, err)
+			return false
+		}
+		session.setState(StateDegraded, err)
+		delay := policy.delay(attempt)
+		session.logger.Println("WARN: [VAULT] Login attempt " + strconv.Itoa(attempt) + " failed, retrying in " + delay.String() + ": " + err.Error())
+		select {
+		case <-session.ctx.Done():
+			return false
+		case <-time.After(delay):
 		}
 	}
 }
 
 // Adapted from https://github.com/hashicorp/vault-examples/blob/main/examples/token-renewal/go/example.go
-func (vault *Vault) runTokenWatcher() error {
-	if vault.loginToken == nil {
+func (session *authSession) runTokenWatcher() error {
+	loginToken := session.getLoginToken()
+	if loginToken == nil {
 		return errors.New("token is nil")
 	}
 	watcherInput := &vaultApi.LifetimeWatcherInput{
-		Secret:    vault.loginToken,
-		Increment: 3600,
+		Secret:    loginToken,
+		Increment: tokenIncrement,
 	}
-	watcher, err := vault.client.NewLifetimeWatcher(watcherInput)
+	watcher, err := session.client.NewLifetimeWatcher(watcherInput)
 	if err != nil {
 		return errors.New("unable to initialize new lifetime watcher for renewing auth token: " + err.Error())
 	}
 
-	go watcher.Start()
-	defer watcher.Stop()
+	watcherDone := make(chan struct{})
+	go func() {
+		watcher.Start()
+		close(watcherDone)
+	}()
+	defer func() {
+		watcher.Stop()
+		<-watcherDone
+	}()
 
 	for {
 		select {
+		case <-session.ctx.Done():
+			return nil
+
+		case <-session.tokenSwap:
+			return errTokenSwapped
+
 		case err := <-watcher.DoneCh():
 			if err != nil {
 				return err
 			}
 			// This occurs once the token has reached max TTL.
-			log.Printf("INFO: [VAULT] Token can no longer be renewed. Re-attempting login.")
-			return vault.login()
+			session.logger.Printf("INFO: [VAULT] Token can no longer be renewed. Re-attempting login.")
+			return nil
 
 		// Successfully completed renewal
 		case renewal := <-watcher.RenewCh():
-			log.Printf("INFO: [VAULT] Successfully renewed vault token")
-			vault.loginToken = renewal.Secret
+			session.logger.Printf("INFO: [VAULT] Successfully renewed vault token")
+			session.setRenewedLoginToken(renewal.Secret)
 		}
 	}
 }
 
-func (vault *Vault) login() (err error) {
-	temp, err := vault.client.Auth().Login(vault.ctx, vault.vaultJwt)
+func (session *authSession) login() (err error) {
+	return session.loginCtx(session.ctx)
+}
+
+func (session *authSession) loginCtx(ctx context.Context) (err error) {
+	session.loginMux.Lock()
+	defer session.loginMux.Unlock()
+	temp, err := session.client.Auth().Login(ctx, session.vaultJwt)
 	if err != nil {
 		return err
 	}
-	vault.loginToken = temp
+	session.setLoginToken(temp)
 	return nil
 }
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// Configures how secrets are decoded by Get and GetVersion
+type DecodeOption func(*json.Decoder)
+
+// Lets decoding fail if the secret contains fields that are not present in the target type
+func DisallowUnknownFields() DecodeOption {
+	return func(decoder *json.Decoder) {
+		decoder.DisallowUnknownFields()
+	}
+}
+
+// Reads the secret with the specified key and decodes it into a value of type T
+func Get[T any](v *Vault, key string, opts ...DecodeOption) (T, error) {
+	return GetCtx[T](context.Background(), v, key, opts...)
+}
+
+// Same as Get, but the request is bound to the provided context
+func GetCtx[T any](ctx context.Context, v *Vault, key string, opts ...DecodeOption) (result T, err error) {
+	m, err := v.ReadCtx(ctx, key)
+	if err != nil {
+		return result, err
+	}
+	return decode[T](m, opts...)
+}
+
+// Reads the secret with the specified key and version and decodes it into a value of type T
+func GetVersion[T any](v *Vault, key string, version int, opts ...DecodeOption) (T, error) {
+	return GetVersionCtx[T](context.Background(), v, key, version, opts...)
+}
+
+// Same as GetVersion, but the request is bound to the provided context
+func GetVersionCtx[T any](ctx context.Context, v *Vault, key string, version int, opts ...DecodeOption) (result T, err error) {
+	m, err := v.ReadVersionCtx(ctx, key, version)
+	if err != nil {
+		return result, err
+	}
+	return decode[T](m, opts...)
+}
+
+// Encodes the value and writes it as a secret with the specified key
+func Put[T any](v *Vault, key string, value T) error {
+	return PutCtx(context.Background(), v, key, value)
+}
+
+// Same as Put, but the request is bound to the provided context
+func PutCtx[T any](ctx context.Context, v *Vault, key string, value T) error {
+	return v.WriteInterfaceCtx(ctx, key, value)
+}
+
+func decode[T any](m map[string]interface{}, opts ...DecodeOption) (result T, err error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return result, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	for _, opt := range opts {
+		opt(decoder)
+	}
+	err = decoder.Decode(&result)
+	return result, err
+}
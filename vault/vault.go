@@ -27,83 +27,200 @@ import (
 )
 
 type Vault struct {
-	vaultJwt    *vaultjwt.VaultJwt
-	client      *vaultApi.Client
-	loginToken  *vaultApi.Secret
-	vaultEngine string
-	ctx         context.Context
+	*authSession
+	vaultEngine     string
+	kvVersion       int
+	casRetries      int
+	walkConcurrency int
+	owner           bool // false for handles created with Engine
 }
 
 // Creates a new vault with JWT authentication. Your vault instance must be configured accordingly.
+// The login token is renewed in the background until ctx is cancelled or Close is called.
 func NewVault(ctx context.Context, vaultUrl, vaultRole, authUrl, authRealm, authClientId, authClientSecret, vaultEngine string) (*Vault, error) {
-	vaultJwt := vaultjwt.New(authUrl, authClientId, authClientSecret, authRealm, vaultRole)
-	vc := vaultApi.DefaultConfig()
-	vc.Address = vaultUrl
-	client, err := vaultApi.NewClient(vc)
+	return New(ctx, WithAddress(vaultUrl), WithRole(vaultRole), WithKeycloak(authUrl, authRealm, authClientId, authClientSecret), WithEngine(vaultEngine))
+}
+
+// Creates a new vault with JWT authentication configured by the provided options. Address, role, keycloak and engine are required.
+// The login token is renewed in the background until ctx is cancelled or Close is called.
+func New(ctx context.Context, opts ...Option) (*Vault, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	err := o.validate()
 	if err != nil {
 		return nil, err
 	}
-	loginToken, err := client.Auth().Login(ctx, vaultJwt)
+	vaultJwt := vaultjwt.New(o.authUrl, o.authClientId, o.authClientSecret, o.authRealm, o.role)
+	vaultJwt.SetLogger(o.logger)
+	vc := vaultApi.DefaultConfig()
+	vc.Address = o.address
+	if o.httpClient != nil {
+		vc.HttpClient = o.httpClient
+	}
+	if o.tlsConfig != nil {
+		vc.HttpClient, err = withTLS(vc.HttpClient, o.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if o.httpClient != nil || o.tlsConfig != nil {
+		vaultJwt.SetHttpClient(vc.HttpClient)
+	}
+	client, err := vaultApi.NewClient(vc)
 	if err != nil {
 		return nil, err
 	}
-	if !loginToken.Auth.Renewable {
+	var fallback *fallbackStore
+	if o.fallback != nil {
+		fallback, err = newFallbackStore(*o.fallback, o.logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	state := StateAuthenticated
+	loginToken, loginErr := client.Auth().Login(ctx, vaultJwt)
+	if loginErr != nil {
+		if fallback == nil || !isUnavailable(loginErr) {
+			return nil, loginErr
+		}
+		o.logger.Println("WARN: [VAULT] Vault unavailable, starting with fallback: " + loginErr.Error())
+		state = StateDegraded
+	} else if !loginToken.Auth.Renewable {
 		return nil, errors.New("token is not renewable, please check vault config")
 	}
+	var cache *readCache
+	if o.cache != nil {
+		cache = newReadCache(*o.cache)
+	}
+	ctx, cancel := context.WithCancel(ctx)
 	vault := &Vault{
-		vaultJwt:    vaultJwt,
-		client:      client,
-		vaultEngine: vaultEngine,
-		loginToken:  loginToken,
-		ctx:         ctx,
+		authSession: &authSession{
+			vaultJwt:   vaultJwt,
+			client:     client,
+			loginToken: loginToken,
+			ctx:        ctx,
+			cancel:     cancel,
+			done:       make(chan struct{}),
+			tokenSwap:  make(chan struct{}, 1),
+			backoff:    o.backoff,
+			state:      state,
+			lastErr:    loginErr,
+			logger:     o.logger,
+			cache:      cache,
+			fallback:   fallback,
+		},
+		vaultEngine:     o.engine,
+		kvVersion:       o.kvVersion,
+		casRetries:      o.casRetries,
+		walkConcurrency: o.walkConcurrency,
+		owner:           true,
+	}
+	if vault.kvVersion == 0 {
+		vault.initKVVersion(ctx)
 	}
 	go vault.manageTokenLifecycle()
-	return vault, err
+	return vault, nil
 }
 
-// Reads the secret with the specified key. Returns an error if the secret is not present.
-func (vault *Vault) Read(key string) (map[string]interface{}, error) {
-	secret, err := vault.client.Logical().Read(vault.vaultEngine + "/data/" + key)
-	if err != nil {
-		return nil, err
-	}
-	if secret == nil {
-		return nil, errors.New("not found")
+// Stops the background token renewal and waits until it has terminated. The login token stays valid until it expires.
+// Has no effect on handles created with Engine.
+func (vault *Vault) Close() error {
+	if !vault.owner {
+		return nil
 	}
-	data, ok := secret.Data["data"]
-	if !ok {
-		return map[string]interface{}{}, errors.New("unexpected type")
+	vault.cancel()
+	<-vault.done
+	vault.setState(StateClosed, nil)
+	return nil
+}
+
+// Stops the background token renewal like Close and revokes the login token afterwards.
+// Has no effect on handles created with Engine.
+func (vault *Vault) CloseAndRevoke(ctx context.Context) error {
+	if !vault.owner {
+		return nil
 	}
-	m, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, errors.New("unexpected type of keys")
+	err := vault.Close()
+	if err != nil {
+		return err
 	}
-	return m, nil
+	return vault.client.Auth().Token().RevokeSelfWithContext(ctx, "")
+}
+
+// Reads the secret with the specified key. Returns ErrNotFound if the secret is not present.
+func (vault *Vault) Read(key string) (map[string]interface{}, error) {
+	return vault.ReadCtx(context.Background(), key)
+}
+
+// Same as Read, but the request is bound to the provided context
+func (vault *Vault) ReadCtx(ctx context.Context, key string) (map[string]interface{}, error) {
+	result, err := vault.ReadWithInfoCtx(ctx, key)
+	return result.Data, err
 }
 
-// Reads the secret with the specified key and version. Returns an error if the version or the secret is not present.
+// Reads the secret with the specified key and version. Returns ErrNotFound if the version or the secret is not present.
 func (vault *Vault) ReadVersion(key string, version int) (map[string]interface{}, error) {
-	secret, err := vault.client.Logical().ReadWithData(vault.vaultEngine+"/data/"+key, map[string][]string{"version": {strconv.Itoa(version)}})
+	return vault.ReadVersionCtx(context.Background(), key, version)
+}
+
+// Same as ReadVersion, but the request is bound to the provided context
+func (vault *Vault) ReadVersionCtx(ctx context.Context, key string, version int) (map[string]interface{}, error) {
+	data, _, err := vault.readSecret(ctx, key, version)
+	return data, err
+}
+
+// Reads data and version metadata of the secret. Version 0 reads the current version.
+// If the version is deleted or destroyed ErrNotFound is returned together with its metadata.
+func (vault *Vault) readSecret(ctx context.Context, key string, version int) (map[string]interface{}, *Metadata, error) {
+	if version > 0 && vault.kvVersion == 1 {
+		return nil, nil, ErrUnsupported
+	}
+	path := vault.path("data", key)
+	var params map[string][]string
+	if version > 0 {
+		params = map[string][]string{"version": {strconv.Itoa(version)}}
+	}
+	secret, err := vault.client.Logical().ReadWithDataWithContext(ctx, path, params)
 	if err != nil {
-		return nil, err
+		return nil, nil, wrapError(err, path)
 	}
 	if secret == nil {
-		return nil, errors.New("not found")
+		return nil, nil, ErrNotFound
+	}
+	if vault.kvVersion == 1 { // KV version 1 secrets have no metadata, a field named metadata is user data
+		return secret.Data, nil, nil
+	}
+	var meta *Metadata
+	if metaMap, ok := secret.Data["metadata"].(map[string]interface{}); ok {
+		meta, err = getMetadata(metaMap)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 	data, ok := secret.Data["data"]
+	if ok && data == nil { // deleted or destroyed
+		return nil, meta, ErrNotFound
+	}
 	if !ok {
-		return map[string]interface{}{}, errors.New("unexpected type")
+		return map[string]interface{}{}, nil, errors.New("unexpected type")
 	}
 	m, ok := data.(map[string]interface{})
 	if !ok {
-		return nil, errors.New("unexpected type of keys")
+		return nil, nil, errors.New("unexpected type of keys")
 	}
-	return m, nil
+	return m, meta, nil
 }
 
 // Reads the secret with the specified key and unmarshal it into the provided interface. Returns an error if the secret is not present or secret could not be unmarshalled.
 func (vault *Vault) ReadInterface(key string, target interface{}) error {
-	m, err := vault.Read(key)
+	return vault.ReadInterfaceCtx(context.Background(), key, target)
+}
+
+// Same as ReadInterface, but the request is bound to the provided context
+func (vault *Vault) ReadInterfaceCtx(ctx context.Context, key string, target interface{}) error {
+	m, err := vault.ReadCtx(ctx, key)
 	if err != nil {
 		return err
 	}
@@ -116,7 +233,12 @@ func (vault *Vault) ReadInterface(key string, target interface{}) error {
 
 // Reads the secret with the specified key and version. Returns an error if the version or the secret is not present. and unmarshal it into the provided interface. Returns an error if the secret is not present or secret could not be unmarshalled.
 func (vault *Vault) ReadInterfaceVersion(key string, target interface{}, version int) error {
-	m, err := vault.ReadVersion(key, version)
+	return vault.ReadInterfaceVersionCtx(context.Background(), key, target, version)
+}
+
+// Same as ReadInterfaceVersion, but the request is bound to the provided context
+func (vault *Vault) ReadInterfaceVersionCtx(ctx context.Context, key string, target interface{}, version int) error {
+	m, err := vault.ReadVersionCtx(ctx, key, version)
 	if err != nil {
 		return err
 	}
@@ -129,12 +251,48 @@ func (vault *Vault) ReadInterfaceVersion(key string, target interface{}, version
 
 // Writes the data as a secret with the specified key
 func (vault *Vault) Write(key string, data map[string]interface{}) error {
-	_, err := vault.client.Logical().Write(vault.vaultEngine+"/data/"+key, map[string]interface{}{"data": data})
+	return vault.WriteCtx(context.Background(), key, data)
+}
+
+// Same as Write, but the request is bound to the provided context
+func (vault *Vault) WriteCtx(ctx context.Context, key string, data map[string]interface{}) error {
+	_, err := vault.writeSecret(ctx, key, data, nil)
 	return err
 }
 
+// Writes the data and returns the metadata of the created version. If cas is set, the write only succeeds if it matches the current version.
+func (vault *Vault) writeSecret(ctx context.Context, key string, data map[string]interface{}, cas *int) (*Metadata, error) {
+	defer vault.invalidate(key)
+	if vault.kvVersion == 1 {
+		if cas != nil {
+			return nil, ErrUnsupported
+		}
+		path := vault.path("data", key)
+		_, err := vault.client.Logical().WriteWithContext(ctx, path, data)
+		return nil, wrapError(err, path)
+	}
+	path := vault.path("data", key)
+	body := map[string]interface{}{"data": data}
+	if cas != nil {
+		body["options"] = map[string]interface{}{"cas": *cas}
+	}
+	secret, err := vault.client.Logical().WriteWithContext(ctx, path, body)
+	if err != nil {
+		return nil, wrapError(err, path)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+	return getMetadata(secret.Data)
+}
+
 // Marshals the interface and writes the data as a secret with the specified key
 func (vault *Vault) WriteInterface(key string, data interface{}) error {
+	return vault.WriteInterfaceCtx(context.Background(), key, data)
+}
+
+// Same as WriteInterface, but the request is bound to the provided context
+func (vault *Vault) WriteInterfaceCtx(ctx context.Context, key string, data interface{}) error {
 	b, err := json.Marshal(data)
 	if err != nil {
 		return err
@@ -145,18 +303,35 @@ func (vault *Vault) WriteInterface(key string, data interface{}) error {
 	if err != nil {
 		return err
 	}
-	return vault.Write(key, m)
+	return vault.WriteCtx(ctx, key, m)
 }
 
 // Deletes the secret with the specified key. Deleted secrets can be undeleted with Undelete
 func (vault *Vault) Delete(key string) error {
-	_, err := vault.client.Logical().Delete(vault.vaultEngine + "/data/" + key)
-	return err
+	return vault.DeleteCtx(context.Background(), key)
+}
+
+// Same as Delete, but the request is bound to the provided context
+func (vault *Vault) DeleteCtx(ctx context.Context, key string) error {
+	defer vault.invalidate(key)
+	path := vault.path("data", key)
+	_, err := vault.client.Logical().DeleteWithContext(ctx, path)
+	return wrapError(err, path)
 }
 
 // Undeletes the secret with the specified key
 func (vault *Vault) Undelete(key string, versions []int) error {
-	r := vault.client.NewRequest(http.MethodPost, "/v1/"+vault.vaultEngine+"/undelete/"+key)
+	return vault.UndeleteCtx(context.Background(), key, versions)
+}
+
+// Same as Undelete, but the request is bound to the provided context
+func (vault *Vault) UndeleteCtx(ctx context.Context, key string, versions []int) error {
+	defer vault.invalidate(key)
+	if err := vault.requireVersioning(); err != nil {
+		return err
+	}
+	path := vault.path("undelete", key)
+	r := vault.client.NewRequest(http.MethodPost, "/v1/"+path)
 	strVersions := make([]string, len(versions))
 	for i := range versions {
 		strVersions[i] = strconv.Itoa(versions[i])
@@ -167,38 +342,43 @@ func (vault *Vault) Undelete(key string, versions []int) error {
 	}
 	r.BodyBytes = bodyBytes
 
-	resp, err := vault.performRequest(r)
-	if err != nil {
-		return err
-	}
+	resp, err := vault.performRequest(ctx, r)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
-	if resp != nil && resp.StatusCode < 299 {
-		return nil
-	}
-	return errors.New("unexpected status code " + strconv.Itoa(resp.StatusCode))
+	return checkResponse(resp, err, path)
 }
 
 // Permanently deletes all versions of the secret with the specified key. WARNING: This action can not be undone!
 func (vault *Vault) Purge(key string) error {
-	r := vault.client.NewRequest(http.MethodDelete, "/v1/"+vault.vaultEngine+"/metadata/"+key)
-	resp, err := vault.performRequest(r)
-	if err != nil {
-		return err
-	}
+	return vault.PurgeCtx(context.Background(), key)
+}
+
+// Same as Purge, but the request is bound to the provided context
+func (vault *Vault) PurgeCtx(ctx context.Context, key string) error {
+	defer vault.invalidate(key)
+	path := vault.path("metadata", key)
+	r := vault.client.NewRequest(http.MethodDelete, "/v1/"+path)
+	resp, err := vault.performRequest(ctx, r)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
-	if resp != nil && resp.StatusCode < 299 {
-		return nil
-	}
-	return errors.New("unexpected status code " + strconv.Itoa(resp.StatusCode))
+	return checkResponse(resp, err, path)
 }
 
 // Permanently deletes the specified versions of the secret with the specified key. WARNING: This action can not be undone!
 func (vault *Vault) DestroyVersions(key string, versions []int) error {
-	r := vault.client.NewRequest(http.MethodPost, "/v1/"+vault.vaultEngine+"/destroy/"+key)
+	return vault.DestroyVersionsCtx(context.Background(), key, versions)
+}
+
+// Same as DestroyVersions, but the request is bound to the provided context
+func (vault *Vault) DestroyVersionsCtx(ctx context.Context, key string, versions []int) error {
+	defer vault.invalidate(key)
+	if err := vault.requireVersioning(); err != nil {
+		return err
+	}
+	path := vault.path("destroy", key)
+	r := vault.client.NewRequest(http.MethodPost, "/v1/"+path)
 	strVersions := make([]string, len(versions))
 	for i := range versions {
 		strVersions[i] = strconv.Itoa(versions[i])
@@ -209,54 +389,41 @@ func (vault *Vault) DestroyVersions(key string, versions []int) error {
 	}
 	r.BodyBytes = bodyBytes
 
-	resp, err := vault.performRequest(r)
-	if err != nil {
-		return err
-	}
+	resp, err := vault.performRequest(ctx, r)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
-	if resp != nil && resp.StatusCode < 299 {
-		return nil
-	}
-	return errors.New("unexpected status code " + strconv.Itoa(resp.StatusCode))
+	return checkResponse(resp, err, path)
 }
 
 // Lists all accessible keys in the vault engine
 func (vault *Vault) ListKeys() ([]string, error) {
-	secret, err := vault.client.Logical().List(vault.vaultEngine + "/metadata")
-	if err != nil {
-		return nil, err
-	}
-	if secret == nil {
-		return []string{}, nil
-	}
-	keys, ok := secret.Data["keys"]
-	if !ok {
-		return []string{}, nil
-	}
+	return vault.ListKeysCtx(context.Background())
+}
 
-	keySlice, ok := keys.([]interface{})
-	if !ok {
-		return nil, errors.New("unexpected type of keys")
-	}
-	keysStringSlice := []string{}
-	for _, key := range keySlice {
-		keyString, ok := key.(string)
-		if !ok {
-			return nil, errors.New("unexpected key type")
-		}
-		keysStringSlice = append(keysStringSlice, keyString)
-	}
-	return keysStringSlice, nil
+// Same as ListKeys, but the request is bound to the provided context
+func (vault *Vault) ListKeysCtx(ctx context.Context) ([]string, error) {
+	return vault.listPath(ctx, "")
 }
 
-// Provides metadata for the secret with the specified key
+// Provides metadata for the current version of the secret with the specified key. See GetSecretMetadata for the metadata of all versions.
 func (vault *Vault) GetMetadata(key string) (*Metadata, error) {
-	secret, err := vault.client.Logical().Read(vault.vaultEngine + "/data/" + key)
-	if err != nil {
+	return vault.GetMetadataCtx(context.Background(), key)
+}
+
+// Same as GetMetadata, but the request is bound to the provided context
+func (vault *Vault) GetMetadataCtx(ctx context.Context, key string) (*Metadata, error) {
+	if err := vault.requireVersioning(); err != nil {
 		return nil, err
 	}
+	path := vault.path("data", key)
+	secret, err := vault.client.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		return nil, wrapError(err, path)
+	}
+	if secret == nil {
+		return nil, ErrNotFound
+	}
 	data, ok := secret.Data["metadata"]
 	if !ok {
 		return nil, errors.New("unexpected type")
@@ -273,8 +440,6 @@ func (vault *Vault) GetMetadata(key string) (*Metadata, error) {
 
 }
 
-func (vault *Vault) performRequest(r *vaultApi.Request) (resp *vaultApi.Response, err error) {
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
+func (vault *Vault) performRequest(ctx context.Context, r *vaultApi.Request) (resp *vaultApi.Response, err error) {
 	return vault.client.RawRequestWithContext(ctx, r)
 }
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vaultjwt
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+var (
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrSealed           = errors.New("vault is sealed")
+)
+
+// Returned if keycloak or the vault JWT login rejects a request
+type AuthError struct {
+	Endpoint   string // url of the failed request
+	StatusCode int
+	Body       string
+	fromVault  bool // the request was sent to vault instead of keycloak
+}
+
+func (e *AuthError) Error() string {
+	return "access denied by " + e.Endpoint + ": status code " + strconv.Itoa(e.StatusCode) + ", " + e.Body
+}
+
+// Matches ErrPermissionDenied if the request was rejected with 401 or 403 and ErrSealed if vault responded with 503
+func (e *AuthError) Is(target error) bool {
+	switch target {
+	case ErrPermissionDenied:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrSealed:
+		return e.fromVault && e.StatusCode == http.StatusServiceUnavailable
+	default:
+		return false
+	}
+}
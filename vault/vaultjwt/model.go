@@ -17,6 +17,8 @@
 package vaultjwt
 
 import (
+	"log"
+	"net/http"
 	"time"
 )
 
@@ -26,6 +28,8 @@ type VaultJwt struct {
 	authClientSecret string
 	authRealm        string
 	vaultRole        string
+	httpClient       *http.Client
+	logger           *log.Logger
 }
 
 type LoginBody struct {
@@ -20,13 +20,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	vault "github.com/hashicorp/vault/api"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -38,13 +36,27 @@ func New(authEndpoint, authClientId, authClientSecret, authRealm, vaultRole stri
 		authClientSecret: authClientSecret,
 		authRealm:        authRealm,
 		vaultRole:        vaultRole,
+		logger:           log.Default(),
 	}
 }
 
+// Sets the HTTP client used for requests to keycloak and vault. Defaults to a client with the timeout of the vault client.
+func (this *VaultJwt) SetHttpClient(client *http.Client) {
+	this.httpClient = client
+}
+
+// Sets the logger used to report failed keycloak requests. Defaults to the standard logger.
+func (this *VaultJwt) SetLogger(logger *log.Logger) {
+	this.logger = logger
+}
+
 // Implements vault.AuthMethod
 func (this *VaultJwt) Login(ctx context.Context, client *vault.Client) (secret *vault.Secret, err error) {
-	http.DefaultClient.Timeout = client.ClientTimeout()
-	jwt, err := getOpenidToken(this.authEndpoint, this.authClientId, this.authClientSecret, this.authRealm)
+	httpClient := this.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: client.ClientTimeout()}
+	}
+	jwt, err := this.getOpenidToken(ctx, httpClient)
 	if err != nil {
 		return nil, err
 	}
@@ -67,14 +79,14 @@ func (this *VaultJwt) Login(ctx context.Context, client *vault.Client) (secret *
 	}
 	req.Header.Set("content-type", "application/json; charset=UTF-8")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode > 299 {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, errors.New("unexpected status code from vault: " + strconv.Itoa(resp.StatusCode) + ", " + string(body))
+		return nil, &AuthError{Endpoint: remote, StatusCode: resp.StatusCode, Body: string(body), fromVault: true}
 	}
 
 	secret = &vault.Secret{}
@@ -83,23 +95,30 @@ func (this *VaultJwt) Login(ctx context.Context, client *vault.Client) (secret *
 	return
 }
 
-func getOpenidToken(authEndpoint, authClientId, authClientSecret, authRealm string) (token *OpenidToken, err error) {
+func (this *VaultJwt) getOpenidToken(ctx context.Context, httpClient *http.Client) (token *OpenidToken, err error) {
 	requesttime := time.Now()
-	resp, err := http.PostForm(authEndpoint+"/auth/realms/"+authRealm+"/protocol/openid-connect/token", url.Values{
-		"client_id":     {authClientId},
-		"client_secret": {authClientSecret},
+	endpoint := this.authEndpoint + "/auth/realms/" + this.authRealm + "/protocol/openid-connect/token"
+	form := url.Values{
+		"client_id":     {this.authClientId},
+		"client_secret": {this.authClientSecret},
 		"grant_type":    {"client_credentials"},
-	})
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := httpClient.Do(req)
 
 	if err != nil {
-		log.Println("ERROR: getOpenidToken::PostForm()", err)
+		this.logger.Println("ERROR: getOpenidToken::Do()", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		log.Println("ERROR: getOpenidToken()", resp.StatusCode, string(body))
-		err = errors.New("access denied")
+		this.logger.Println("ERROR: getOpenidToken()", resp.StatusCode, string(body))
+		err = &AuthError{Endpoint: endpoint, StatusCode: resp.StatusCode, Body: string(body)}
 		return
 	}
 
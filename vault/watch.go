@@ -0,0 +1,261 @@
+/*
+ * Copyright 2021 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Used by Watch and WatchPrefix if the interval is not positive
+const DefaultWatchInterval = 30 * time.Second
+
+type ChangeType int
+
+const (
+	SecretCreated ChangeType = iota // the secret became readable, e.g. after it was written for the first time or undeleted
+	SecretUpdated                   // a new version was written
+	SecretDeleted                   // the current version was deleted or destroyed. For KV version 1 engines the secret was removed.
+	SecretPurged                    // all versions and the metadata were removed
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case SecretCreated:
+		return "created"
+	case SecretUpdated:
+		return "updated"
+	case SecretDeleted:
+		return "deleted"
+	case SecretPurged:
+		return "purged"
+	default:
+		return "unknown"
+	}
+}
+
+// Change of a watched secret. OldData is nil if the secret was not readable before, NewData is nil if it is no longer readable.
+type Change struct {
+	Key     string
+	Type    ChangeType
+	Version int // current version after the change, 0 for KV version 1 engines and purged secrets
+	OldData map[string]interface{}
+	NewData map[string]interface{}
+}
+
+// Polls the secret with the specified key every interval and emits its changes. Only the metadata is requested, unless
+// the current version changed. Errors are logged and the polling continues. The channel is closed after ctx is done.
+func (vault *Vault) Watch(ctx context.Context, key string, interval time.Duration) <-chan Change {
+	return vault.watch(ctx, interval, nil, vault.keyPoller(key))
+}
+
+// Same as Watch, but emits the changes of all secrets below the prefix. Secrets are listed on every poll.
+func (vault *Vault) WatchPrefix(ctx context.Context, prefix string, interval time.Duration) <-chan Change {
+	return vault.watch(ctx, interval, nil, func(ctx context.Context, previous map[string]watchState) (map[string]watchState, error) {
+		return vault.pollPrefix(ctx, prefix, previous)
+	})
+}
+
+// Observed state of a secret. Secrets that do not exist are not stored in maps of watchState.
+type watchState struct {
+	version int
+	updated time.Time
+	live    bool // the current version is readable
+	data    map[string]interface{}
+}
+
+type pollFunc func(ctx context.Context, previous map[string]watchState) (map[string]watchState, error)
+
+func (vault *Vault) keyPoller(key string) pollFunc {
+	return func(ctx context.Context, previous map[string]watchState) (map[string]watchState, error) {
+		state, exists, err := vault.pollKey(ctx, key, previous[key])
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return map[string]watchState{}, nil
+		}
+		return map[string]watchState{key: state}, nil
+	}
+}
+
+// Emits the changes between polls. If initial is nil, the first poll is used as initial state.
+func (vault *Vault) watch(ctx context.Context, interval time.Duration, initial map[string]watchState, poll pollFunc) <-chan Change {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	changes := make(chan Change)
+	go func() {
+		defer close(changes)
+		states := initial
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			next, err := poll(ctx, states)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				vault.logger.Println("WARN: [VAULT] Unable to poll watched secrets: " + err.Error())
+			} else if states == nil { // first poll
+				states = next
+			} else {
+				removed := SecretPurged
+				if vault.kvVersion == 1 { // KV version 1 secrets are removed by Delete
+					removed = SecretDeleted
+				}
+				for _, change := range diffStates(states, next, removed) {
+					select {
+					case changes <- change:
+					case <-ctx.Done():
+						return
+					}
+				}
+				states = next
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return changes
+}
+
+// Returns the changes between two polls, sorted by key for deterministic output
+func diffStates(previous map[string]watchState, next map[string]watchState, removed ChangeType) []Change {
+	changes := []Change{}
+	for _, key := range sortedKeys(previous, next) {
+		old, existed := previous[key]
+		current, exists := next[key]
+		change := Change{Key: key, Version: current.version, OldData: old.data, NewData: current.data}
+		switch {
+		case !old.live && current.live:
+			change.Type = SecretCreated
+		case old.live && current.live && (old.version != current.version || !reflect.DeepEqual(old.data, current.data)):
+			change.Type = SecretUpdated
+		case existed && !exists:
+			change.Type = removed
+		case old.live && !current.live:
+			change.Type = SecretDeleted
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func sortedKeys(maps ...map[string]watchState) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Returns the state of a single secret and false if it does not exist. Data is only read if the metadata differs from the previous state.
+func (vault *Vault) pollKey(ctx context.Context, key string, previous watchState) (watchState, bool, error) {
+	if vault.kvVersion == 1 {
+		return vault.pollKeyV1(ctx, key)
+	}
+	meta, err := vault.GetSecretMetadataCtx(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return watchState{}, false, nil
+	}
+	if err != nil {
+		return watchState{}, false, err
+	}
+	state, err := vault.stateFromMetadata(ctx, key, meta, previous)
+	return state, err == nil, err
+}
+
+func (vault *Vault) stateFromMetadata(ctx context.Context, key string, meta *SecretMetadata, previous watchState) (watchState, error) {
+	state := watchState{version: meta.CurrentVersion, updated: meta.UpdatedTime.Time, live: meta.DeletionState() == NotDeleted}
+	if !state.live {
+		return state, nil
+	}
+	if previous.live && previous.version == state.version && previous.updated.Equal(state.updated) {
+		state.data = previous.data
+		return state, nil
+	}
+	data, _, err := vault.readSecret(ctx, key, state.version)
+	if errors.Is(err, ErrNotFound) { // deleted since the metadata was read
+		state.live = false
+		return state, nil
+	}
+	if err != nil {
+		return watchState{}, err
+	}
+	state.data = data
+	return state, nil
+}
+
+// KV version 1 engines have no metadata, so the data is compared instead
+func (vault *Vault) pollKeyV1(ctx context.Context, key string) (watchState, bool, error) {
+	data, _, err := vault.readSecret(ctx, key, 0)
+	if errors.Is(err, ErrNotFound) {
+		return watchState{}, false, nil
+	}
+	if err != nil {
+		return watchState{}, false, err
+	}
+	return watchState{live: true, data: data}, true, nil
+}
+
+func (vault *Vault) pollPrefix(ctx context.Context, prefix string, previous map[string]watchState) (map[string]watchState, error) {
+	states := map[string]watchState{}
+	if vault.kvVersion == 1 {
+		keys, err := vault.ListRecursiveCtx(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			state, exists, err := vault.pollKeyV1(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				states[key] = state
+			}
+		}
+		return states, nil
+	}
+	results, err := vault.FindCtx(ctx, prefix, Filter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		state, err := vault.stateFromMetadata(ctx, result.Path, result.Metadata, previous[result.Path])
+		if err != nil {
+			return nil, err
+		}
+		states[result.Path] = state
+	}
+	return states, nil
+}